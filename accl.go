@@ -27,14 +27,17 @@ func (a ACCL) Roll() float64 {
 	return math.Atan(tanroll) * 180. / math.Pi
 }
 
-// AcclWithTime TODO find a way to generic it, []any is impossible
-func AcclWithTime(values []*telemetry.TELEM) (all []Timely) {
+// GYRO gyroscope sample in rad/s
+type GYRO telemetry.GYRO
+
+// samplesWithTime samples of each telemetry packet spread until next packet
+func samplesWithTime[T any](values []*telemetry.TELEM, samples func(*telemetry.TELEM) []T, value func(T) any) (all []Timely) {
 	all = make([]Timely, 0)
 	for i, v := range values {
 		if v.Time.Time.IsZero() {
 			continue
 		}
-		available := v.Accl
+		available := samples(v)
 		if len(available) == 0 {
 			// nothing ?
 			continue
@@ -52,12 +55,24 @@ func AcclWithTime(values []*telemetry.TELEM) (all []Timely) {
 				delta = time.Second.Nanoseconds()
 			}
 		}
-		for j, value := range available {
+		for j, sample := range available {
 			all = append(all, Timely{
 				Time:  v.Time.Time.Add(time.Duration((int64(j) * delta) / int64(len(available)))),
-				Value: ACCL(value),
+				Value: value(sample),
 			})
 		}
 	}
 	return
 }
+
+// AcclWithTime accelerometer samples with their time
+func AcclWithTime(values []*telemetry.TELEM) (all []Timely) {
+	return samplesWithTime(values, func(t *telemetry.TELEM) []telemetry.ACCL { return t.Accl },
+		func(a telemetry.ACCL) any { return ACCL(a) })
+}
+
+// GyroWithTime gyroscope samples with their time
+func GyroWithTime(values []*telemetry.TELEM) (all []Timely) {
+	return samplesWithTime(values, func(t *telemetry.TELEM) []telemetry.GYRO { return t.Gyro },
+		func(g telemetry.GYRO) any { return GYRO(g) })
+}
@@ -0,0 +1,235 @@
+package gokart
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchOptions how videos are processed by RunBatch
+type BatchOptions struct {
+	// Workers number of videos processed at the same time, number of CPU when 0
+	Workers int
+	// Store optional cache of parsed sessions
+	Store *Store
+	// Render best lap image of each video in OutDir, using aerial images of Path
+	Render bool
+	Path   string
+	OutDir string
+	Mode   string
+	// Progress called after each video, from a single goroutine
+	Progress func(r BatchResult, done, total int)
+}
+
+// DefaultBatchOptions render best lap colored by acceleration in current directory
+var DefaultBatchOptions = BatchOptions{
+	Render: true,
+	Path:   ".",
+	OutDir: ".",
+	Mode:   "acc",
+}
+
+// BatchResult what was found in one video, Error is set when processing failed,
+// other fields are then filled as far as processing went
+type BatchResult struct {
+	File        string        `json:"file"`
+	Video       VideoInfo     `json:"video"`
+	Track       string        `json:"track,omitempty"`
+	Meta        Metadata      `json:"meta"`
+	Laps        int           `json:"laps"`
+	Best        int           `json:"best"`
+	BestTime    time.Duration `json:"besttime"`
+	Theoretical time.Duration `json:"theoretical"`
+	Image       string        `json:"image,omitempty"`
+	Elapsed     time.Duration `json:"elapsed"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// FindVideos all MP4 files and GPS logs under root, sorted
+func FindVideos(root string) (files []string, err error) {
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isSessionFile(d.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return
+}
+
+// batchImageName best lap image of video, unique even for same names in different folders
+func batchImageName(outDir, file string, index int) string {
+	base := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	return filepath.Join(outDir, fmt.Sprintf("%03d_%s_best.png", index, base))
+}
+
+// processVideo all batch steps for one video
+func processVideo(file string, index int, opts BatchOptions) (r BatchResult) {
+	begin := time.Now()
+	r.File = file
+	r.Best = -1
+	var err error
+	defer func() {
+		r.Elapsed = time.Since(begin)
+		if err != nil {
+			r.Error = err.Error()
+		}
+	}()
+	// GPS logs have no video
+	if !IsGPSLog(file) {
+		if r.Video, err = GetVideoInfo(file); err != nil {
+			return
+		}
+	}
+	var s *Session
+	if opts.Store != nil {
+		s, err = opts.Store.Load(file)
+	} else {
+		s, err = LoadSession(file)
+	}
+	if err != nil {
+		return
+	}
+	r.Track = s.Track.Name
+	r.Meta = s.Meta
+	r.Laps = len(s.Laps.CompleteLaps())
+	r.Theoretical = s.Laps.TheroreticalBest()
+	if r.BestTime = s.Laps.BestTime(); r.BestTime == 0 {
+		// nothing to render
+		return
+	}
+	r.Best = s.Laps.Best()
+	if !opts.Render {
+		return
+	}
+	var img image.Image
+	if img, err = s.Laps.DrawLap(opts.Path, opts.Mode, s.GPS, r.Best); err != nil {
+		return
+	}
+	name := batchImageName(opts.OutDir, file, index)
+	f, err := os.Create(name)
+	if err != nil {
+		return
+	}
+	if err = png.Encode(f, img); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Close(); err == nil {
+		r.Image = name
+	}
+	return
+}
+
+// RunBatch process files with a bounded worker pool, an error on one file does
+// not stop others, results are in files order
+func RunBatch(files []string, opts BatchOptions) (results []BatchResult) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if opts.Render {
+		// on error each render fails and reports it
+		os.MkdirAll(opts.OutDir, os.ModePerm)
+	}
+	results = make([]BatchResult, len(files))
+	jobs := make(chan int)
+	done := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, max(1, len(files))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = processVideo(files[i], i, opts)
+				done <- i
+			}
+		}()
+	}
+	go func() {
+		for i := range files {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+	count := 0
+	for i := range done {
+		count++
+		if opts.Progress != nil {
+			opts.Progress(results[i], count, len(files))
+		}
+	}
+	return
+}
+
+// BatchErrors number of failed videos
+func BatchErrors(results []BatchResult) (n int) {
+	for _, r := range results {
+		if r.Error != "" {
+			n++
+		}
+	}
+	return
+}
+
+// WriteBatchSummary text table of all results with best lap of each track
+func WriteBatchSummary(w io.Writer, results []BatchResult) (err error) {
+	type best struct {
+		time time.Duration
+		file string
+	}
+	bests := make(map[string]best)
+	ew := &errWriter{w: w}
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Fprintf(ew, "%-40s ERROR %s\n", r.File, r.Error)
+			continue
+		}
+		fmt.Fprintf(ew, "%-40s %-16s %-12s %3d laps", r.File, r.Track, r.Meta.Driver, r.Laps)
+		if r.BestTime > 0 {
+			fmt.Fprintf(ew, " best %02d %s theoretical %s", r.Best, DurationToChrono(r.BestTime), DurationToChrono(r.Theoretical))
+			if b, ok := bests[r.Track]; !ok || r.BestTime < b.time {
+				bests[r.Track] = best{r.BestTime, r.File}
+			}
+		}
+		fmt.Fprintln(ew)
+	}
+	tracks := make([]string, 0, len(bests))
+	for track := range bests {
+		tracks = append(tracks, track)
+	}
+	sort.Strings(tracks)
+	for _, track := range tracks {
+		fmt.Fprintf(ew, "best on %s: %s in %s\n", track, DurationToChrono(bests[track].time), bests[track].file)
+	}
+	fmt.Fprintf(ew, "%d videos, %d errors\n", len(results), BatchErrors(results))
+	return ew.err
+}
+
+// errWriter keep first write error
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) Write(p []byte) (n int, err error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	n, ew.err = ew.w.Write(p)
+	return n, ew.err
+}
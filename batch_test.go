@@ -0,0 +1,63 @@
+package gokart
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.mp4", "sub/b.MP4", "sub/c.mp4", "notes.txt"} {
+		name = filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(name), os.ModePerm)
+		if err := os.WriteFile(name, []byte("not a video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// GPS logs are processed like videos
+	s := circleSession([]float64{10, 12, 11})
+	tracks := TheWorld.Tracks
+	TheWorld.Tracks = append(slices.Clone(tracks), s.Track)
+	t.Cleanup(func() { TheWorld.Tracks = tracks })
+	if err := os.WriteFile(filepath.Join(dir, "sub", "d.nmea"), []byte(nmeaLog(s.GPS)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := FindVideos(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("found %d videos should be 4", len(files))
+	}
+	opts := DefaultBatchOptions
+	opts.Workers = 2
+	opts.OutDir = t.TempDir()
+	opts.Render = false
+	calls := 0
+	opts.Progress = func(r BatchResult, done, total int) {
+		calls++
+		if done != calls || total != 4 {
+			t.Errorf("wrong progress %d/%d", done, total)
+		}
+	}
+	results := RunBatch(files, opts)
+	if calls != 4 {
+		t.Errorf("progress called %d times should be 4", calls)
+	}
+	// every video fails without stopping others
+	for i, r := range results {
+		if r.File != files[i] || (r.Error == "") != strings.HasSuffix(r.File, ".nmea") {
+			t.Errorf("wrong result %d %+v", i, r)
+		}
+	}
+	if r := results[3]; r.Track != "Circle" || r.Laps != 2 || r.Best != s.Laps.Best() {
+		t.Errorf("wrong GPS log result %+v", r)
+	}
+	var b strings.Builder
+	if err = WriteBatchSummary(&b, results); err != nil || !strings.Contains(b.String(), "4 videos, 3 errors") {
+		t.Errorf("wrong summary %q", b.String())
+	}
+}
@@ -0,0 +1,299 @@
+package gokart
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+)
+
+// ChannelFunc all values of a channel, one per GPS sample, other channels
+// are read from c
+type ChannelFunc func(gps []Timely, c *Channels) ([]float64, error)
+
+// Channel named scalar value of each GPS sample, in SI units, computed from
+// samples or from other channels
+type Channel struct {
+	Name  string
+	Label string
+	// Unit and Factor used for display, shown value is value*Factor
+	Unit    string
+	Factor  float64
+	Compute ChannelFunc
+	Scale   Scale
+	Colors  ColorMap
+}
+
+var (
+	channels      = make(map[string]Channel)
+	channelsMutex sync.RWMutex
+)
+
+// RegisterChannel add or replace a channel, it can then be used by name as a
+// DrawLap mode, in exports and in expressions of other channels
+func RegisterChannel(ch Channel) {
+	if ch.Colors == nil {
+		ch.Colors = Viridis
+	}
+	channelsMutex.Lock()
+	defer channelsMutex.Unlock()
+	channels[ch.Name] = ch
+}
+
+// RegisterExpression channel computed from an expression over other channels,
+// like "speed^2 * curvature", see ParseExpression
+func RegisterExpression(name, label, unit, expression string) (err error) {
+	e, err := ParseExpression(expression)
+	if err != nil {
+		return fmt.Errorf("channel %s:%w", name, err)
+	}
+	RegisterChannel(Channel{
+		Name:  name,
+		Label: label,
+		Unit:  unit,
+		Compute: func(gps []Timely, c *Channels) ([]float64, error) {
+			return e.Eval(c)
+		},
+	})
+	return
+}
+
+// unregisterChannel remove a channel, used by tests
+func unregisterChannel(name string) {
+	channelsMutex.Lock()
+	defer channelsMutex.Unlock()
+	delete(channels, name)
+}
+
+// GetChannel registered channel by name
+func GetChannel(name string) (ch Channel, ok bool) {
+	channelsMutex.RLock()
+	defer channelsMutex.RUnlock()
+	ch, ok = channels[name]
+	return
+}
+
+// ChannelNames names of all registered channels, sorted
+func ChannelNames() (names []string) {
+	channelsMutex.RLock()
+	defer channelsMutex.RUnlock()
+	for name := range channels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return
+}
+
+// Channels values of channels for one GPS series, each channel is computed
+// once when first needed. Not safe for concurrent use.
+type Channels struct {
+	gps     []Timely
+	values  map[string][]float64
+	pending map[string]bool
+}
+
+// NewChannels lazy channels of gps
+func NewChannels(gps []Timely) *Channels {
+	return &Channels{gps: gps, values: make(map[string][]float64), pending: make(map[string]bool)}
+}
+
+// Len number of samples of each channel
+func (c *Channels) Len() int {
+	return len(c.gps)
+}
+
+// GPS samples channels are computed from
+func (c *Channels) GPS() []Timely {
+	return c.gps
+}
+
+// Get values of a registered channel, computed on first call
+func (c *Channels) Get(name string) (values []float64, err error) {
+	if values, ok := c.values[name]; ok {
+		return values, nil
+	}
+	ch, ok := GetChannel(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown channel %s", name)
+	}
+	if c.pending[name] {
+		return nil, fmt.Errorf("channel %s depends on itself", name)
+	}
+	c.pending[name] = true
+	defer delete(c.pending, name)
+	if values, err = ch.Compute(c.gps, c); err != nil {
+		return nil, fmt.Errorf("channel %s:%w", name, err)
+	}
+	if len(values) != len(c.gps) {
+		return nil, fmt.Errorf("channel %s has %d values for %d samples", name, len(values), len(c.gps))
+	}
+	c.values[name] = values
+	return
+}
+
+// Channels of session GPS samples, created on first call
+func (s *Session) Channels() *Channels {
+	if s.channels == nil || len(s.channels.gps) != len(s.GPS) {
+		s.channels = NewChannels(s.GPS)
+	}
+	return s.channels
+}
+
+// Mode DrawLap mode showing channel, values are computed for the whole GPS
+// series the first time it is drawn, NaN when channel can not be computed
+func (ch Channel) Mode() Mode {
+	var mutex sync.Mutex
+	var last *Channels
+	return Mode{
+		Name:   ch.Name,
+		Label:  ch.Label,
+		Unit:   ch.Unit,
+		Factor: ch.Factor,
+		Value: func(gps []Timely, index int) float64 {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if last == nil || len(last.gps) != len(gps) || (len(gps) > 0 && &last.gps[0] != &gps[0]) {
+				last = NewChannels(gps)
+			}
+			values, err := last.Get(ch.Name)
+			if err != nil {
+				return math.NaN()
+			}
+			return values[index]
+		},
+		Scale:  ch.Scale,
+		Colors: ch.Colors,
+	}
+}
+
+// gpsValues channel from a field of GPS5 samples
+func gpsValues(value func(g GPS5) float64) ChannelFunc {
+	return func(gps []Timely, c *Channels) (values []float64, err error) {
+		values = make([]float64, len(gps))
+		for i, g := range gps {
+			values[i] = value(g.Value.(GPS5))
+		}
+		return
+	}
+}
+
+// modeValues channel from a DrawLap value function
+func modeValues(value func(gps []Timely, index int) float64) ChannelFunc {
+	return func(gps []Timely, c *Channels) (values []float64, err error) {
+		values = make([]float64, len(gps))
+		if len(gps) < 2 {
+			return
+		}
+		for i := range gps {
+			values[i] = value(gps, i)
+		}
+		return
+	}
+}
+
+// headings unwrapped heading in radians of each sample, from samples around it
+func headings(gps []Timely, c *Channels) (values []float64, err error) {
+	values = make([]float64, len(gps))
+	for i := range gps {
+		from, to := max(0, i-LAT_DELTA), min(len(gps)-1, i+LAT_DELTA)
+		if from == to {
+			continue
+		}
+		values[i] = heading(gps[from].Value.(GPS5), gps[to].Value.(GPS5))
+		if i > 0 {
+			// continuous with previous one
+			values[i] = values[i-1] + math.Remainder(values[i]-values[i-1], 2*math.Pi)
+		}
+	}
+	return
+}
+
+func init() {
+	RegisterChannel(Channel{
+		Name: "time", Label: "Time", Unit: "s",
+		Compute: func(gps []Timely, c *Channels) (values []float64, err error) {
+			values = make([]float64, len(gps))
+			for i, g := range gps {
+				values[i] = g.Time.Sub(gps[0].Time).Seconds()
+			}
+			return
+		},
+	})
+	RegisterChannel(Channel{
+		Name: "distance", Label: "Distance", Unit: "m",
+		Compute: func(gps []Timely, c *Channels) (values []float64, err error) {
+			values = make([]float64, len(gps))
+			for i := 1; i < len(gps); i++ {
+				values[i] = values[i-1] + Distance(gps[i-1].Value.(GPS5), gps[i].Value.(GPS5))
+			}
+			return
+		},
+	})
+	RegisterChannel(Channel{
+		Name: "speed", Label: "Speed", Unit: "km/h", Factor: 3.6,
+		Compute: gpsValues(func(g GPS5) float64 { return g.Speed3D }),
+		Scale:   Scale{Kind: ScaleMinMax}, Colors: BlueGreenRed,
+	})
+	RegisterChannel(Channel{
+		Name: "altitude", Label: "Altitude", Unit: "m",
+		Compute: gpsValues(func(g GPS5) float64 { return g.Altitude }),
+	})
+	RegisterChannel(Channel{
+		Name: "acc", Label: "Acceleration", Unit: "m/s²",
+		Compute: modeValues(GetAcc), Scale: Scale{Kind: ScaleSymmetric}, Colors: RedWhiteGreen,
+	})
+	RegisterChannel(Channel{
+		Name: "heading", Label: "Heading", Unit: "°", Factor: 180 / math.Pi,
+		Compute: headings,
+	})
+	RegisterChannel(Channel{
+		Name: "yaw", Label: "Yaw rate", Unit: "°/s", Factor: 180 / math.Pi,
+		Compute: func(gps []Timely, c *Channels) ([]float64, error) {
+			return derivativeOf(c, "heading", "time")
+		},
+		Scale: Scale{Kind: ScalePercentile, Percentile: 1}, Colors: Diverging,
+	})
+	RegisterChannel(Channel{
+		Name: "curvature", Label: "Curvature", Unit: "1/m",
+		Compute: func(gps []Timely, c *Channels) ([]float64, error) {
+			return derivativeOf(c, "heading", "distance")
+		},
+		Scale: Scale{Kind: ScalePercentile, Percentile: 1}, Colors: Diverging,
+	})
+	// same lateral acceleration as lateral mode, in g
+	RegisterChannel(Channel{
+		Name: "latg", Label: "Lateral G", Unit: "g",
+		Compute: modeValues(func(gps []Timely, index int) float64 {
+			return GetLateralAcc(gps, index) / STANDARD_GRAVITY
+		}),
+		Scale: Scale{Kind: ScalePercentile, Percentile: 1}, Colors: Diverging,
+	})
+	if err := RegisterExpression("jerk", "Longitudinal jerk", "m/s³", "diff(avg(acc, 4))"); err != nil {
+		panic(err)
+	}
+}
+
+// derivativeOf d(name)/d(by) of channels, centered on each sample
+func derivativeOf(c *Channels, name, by string) (values []float64, err error) {
+	y, err := c.Get(name)
+	if err != nil {
+		return
+	}
+	x, err := c.Get(by)
+	if err != nil {
+		return
+	}
+	return derivative(y, x), nil
+}
+
+// derivative dy/dx centered on each sample, 0 where x does not change
+func derivative(y, x []float64) (d []float64) {
+	d = make([]float64, len(y))
+	for i := range y {
+		from, to := max(0, i-1), min(len(y)-1, i+1)
+		if dx := x[to] - x[from]; dx != 0 {
+			d[i] = (y[to] - y[from]) / dx
+		}
+	}
+	return
+}
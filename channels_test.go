@@ -0,0 +1,110 @@
+package gokart
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"testing"
+)
+
+func TestExpression(t *testing.T) {
+	c := NewChannels(make([]Timely, 3))
+	for s, expected := range map[string]float64{
+		"1 + 2 * 3 ^ 2":   19,
+		"(1 + 2) * 3":     9,
+		"-2^2":            -4,
+		"2^3^2":           512,
+		"10 / 4 - 1":      1.5,
+		"max(1, abs(-3))": 3,
+	} {
+		e, err := ParseExpression(s)
+		if err != nil {
+			t.Errorf("%s:%s", s, err)
+			continue
+		}
+		values, err := e.Eval(c)
+		if err != nil {
+			t.Errorf("%s:%s", s, err)
+			continue
+		}
+		if len(values) != 3 || values[0] != expected {
+			t.Errorf("%s is %v should be %v", s, values, expected)
+		}
+	}
+	for _, s := range []string{"", "speed +", "(speed", "avg(speed)", "foo(1)", "speed $ 2", "1.2.3"} {
+		if _, err := ParseExpression(s); err == nil {
+			t.Errorf("%q should not parse", s)
+		}
+	}
+}
+
+func TestChannels(t *testing.T) {
+	s := circleSession([]float64{10})
+	c := s.Channels()
+	if c != s.Channels() {
+		t.Error("channels should be computed once per session")
+	}
+	i := len(s.GPS) / 2
+	// counter clockwise circle of 100m, turning left at 10m/s
+	for name, expected := range map[string]float64{
+		"speed":     10,
+		"curvature": -0.01,
+		"yaw":       -0.1,
+		"latg":      -1 / STANDARD_GRAVITY,
+	} {
+		values, err := c.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(values[i]-expected) > math.Abs(expected)*0.05 {
+			t.Errorf("%s is %f should be %f", name, values[i], expected)
+		}
+	}
+	latg, _ := c.Get("latg")
+	if lateral, _ := GetMode("lateral"); math.Abs(latg[i]-lateral.Display(lateral.Value(s.GPS, i))) > 1e-12 {
+		t.Errorf("latg %f and lateral mode differ", latg[i])
+	}
+	if _, err := c.Get("unknown"); err == nil {
+		t.Error("unknown channel should fail")
+	}
+	if err := RegisterExpression("test_loop", "Loop", "", "test_loop + 1"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unregisterChannel("test_loop") })
+	if _, err := c.Get("test_loop"); err == nil {
+		t.Error("channel depending on itself should fail")
+	}
+}
+
+func TestCustomChannel(t *testing.T) {
+	if err := RegisterExpression("test_kmh", "Speed", "km/h", "speed * 3.6"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unregisterChannel("test_kmh") })
+	m, ok := GetMode("test_kmh")
+	if !ok {
+		t.Fatal("channel should be a mode")
+	}
+	s := circleSession([]float64{10})
+	if v := m.Value(s.GPS, 5); math.Abs(v-36) > 1e-9 {
+		t.Errorf("mode value is %f should be 36", v)
+	}
+	var b bytes.Buffer
+	if err := s.WriteCSV(&b, "test_kmh", "distance"); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := records[0]
+	if header[len(header)-2] != "test_kmh" || header[len(header)-1] != "distance" {
+		t.Errorf("channels missing in header %v", header)
+	}
+	if v := records[1][len(header)-2]; v != "36" {
+		t.Errorf("first speed is %s should be 36", v)
+	}
+	if err := s.WriteCSV(&b, "unknown"); err == nil {
+		t.Error("unknown channel should fail")
+	}
+}
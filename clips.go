@@ -0,0 +1,240 @@
+package gokart
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// CLIP_EVENTS kinds of ClipEvents
+var CLIP_EVENTS = []string{"best", "laps", "braking", "spin", "impact"}
+
+// ClipConfig how events are detected and cut
+type ClipConfig struct {
+	// PreRoll and PostRoll added before and after each event
+	PreRoll  time.Duration
+	PostRoll time.Duration
+	// BrakeThreshold peak deceleration in m/s² (positive) of a hard braking
+	BrakeThreshold float64
+	// SpinYawRate yaw rate in rad/s above which kart is spinning
+	SpinYawRate float64
+	// SpinMinSpeed speed in m/s below which yaw rate is ignored, it is only
+	// noise when kart is stopped
+	SpinMinSpeed float64
+	// ImpactG acceleration in g above which camera was hit
+	ImpactG float64
+	// Merge events of same kind closer than Merge are one event
+	Merge time.Duration
+}
+
+// DefaultClipConfig 3s before and 2s after, braking over 0.8g, spins over 120°/s
+// above 10 km/h and impacts over 4g
+var DefaultClipConfig = ClipConfig{
+	PreRoll:        3 * time.Second,
+	PostRoll:       2 * time.Second,
+	BrakeThreshold: 0.8 * STANDARD_GRAVITY,
+	SpinYawRate:    120 * math.Pi / 180,
+	SpinMinSpeed:   10 / 3.6,
+	ImpactG:        4,
+	Merge:          time.Second,
+}
+
+// ClipEvent part of a session worth a clip
+type ClipEvent struct {
+	// Kind "best", "lap", "braking", "spin" or "impact"
+	Kind string `json:"kind"`
+	// Lap -1 before first start line
+	Lap   int       `json:"lap"`
+	Start time.Time `json:"start"`
+	Stop  time.Time `json:"stop"`
+	// Peak deceleration in m/s², yaw rate in rad/s or acceleration in g
+	Peak float64 `json:"peak,omitempty"`
+}
+
+// Name file name friendly description
+func (e ClipEvent) Name() string {
+	if e.Kind == "best" || e.Kind == "lap" {
+		return fmt.Sprintf("%s_lap%02d", e.Kind, e.Lap)
+	}
+	return fmt.Sprintf("%s_lap%02d_%s", e.Kind, e.Lap, e.Start.Format("150405"))
+}
+
+func (e ClipEvent) String() string {
+	return fmt.Sprintf("%s lap %02d at %s for %s", e.Kind, e.Lap,
+		e.Start.Format("15:04:05.00"), DurationToChrono(e.Stop.Sub(e.Start)))
+}
+
+// lapEvent whole lap
+func (s *Session) lapEvent(kind string, lap int) ClipEvent {
+	return ClipEvent{Kind: kind, Lap: lap, Start: s.Laps.LapStart(lap), Stop: s.Laps.LapStart(lap + 1)}
+}
+
+// LapClipEvent event of a complete lap
+func (s *Session) LapClipEvent(lap int) (e ClipEvent, err error) {
+	if !slices.Contains(s.Laps.CompleteLaps(), lap) {
+		return e, fmt.Errorf("lap %d is not a complete lap", lap)
+	}
+	return s.lapEvent("lap", lap), nil
+}
+
+// thresholdEvents spans where |values| is over threshold, merged when closer than cfg.Merge
+func thresholdEvents(kind string, series []Timely, values []float64, threshold float64, merge time.Duration) (events []ClipEvent) {
+	for i, v := range values {
+		if math.Abs(v) < threshold {
+			continue
+		}
+		t := series[i].Time
+		if n := len(events); n > 0 && t.Sub(events[n-1].Stop) <= merge {
+			events[n-1].Stop = t
+			events[n-1].Peak = max(events[n-1].Peak, math.Abs(v))
+			continue
+		}
+		events = append(events, ClipEvent{Kind: kind, Start: t, Stop: t, Peak: math.Abs(v)})
+	}
+	return
+}
+
+// yawRates yaw rate in rad/s around vertical from GYRO, vertical being mean
+// ACCL direction, or from GPS heading when there is no gyroscope. Rates are
+// zero when GPS speed is below minSpeed.
+func (s *Session) yawRates(minSpeed float64) (series []Timely, yaw []float64, err error) {
+	if len(s.GYRO) > 0 && len(s.ACCL) > 0 {
+		var up ACCL
+		for _, a := range s.ACCL {
+			v := a.Value.(ACCL)
+			up.X, up.Y, up.Z = up.X+v.X, up.Y+v.Y, up.Z+v.Z
+		}
+		norm := math.Sqrt(up.X*up.X + up.Y*up.Y + up.Z*up.Z)
+		series, yaw = s.GYRO, make([]float64, len(s.GYRO))
+		for i, g := range s.GYRO {
+			v := g.Value.(GYRO)
+			yaw[i] = (v.X*up.X + v.Y*up.Y + v.Z*up.Z) / norm
+		}
+	} else {
+		series = s.GPS
+		if yaw, err = s.Channels().Get("yaw"); err != nil {
+			return
+		}
+		// channel is shared
+		yaw = slices.Clone(yaw)
+	}
+	j := 0
+	for i, g := range series {
+		for j+1 < len(s.GPS) && !s.GPS[j+1].Time.After(g.Time) {
+			j++
+		}
+		if len(s.GPS) == 0 || GetSpeed(s.GPS, j) < minSpeed {
+			yaw[i] = 0
+		}
+	}
+	return
+}
+
+// ClipEvents events of given kinds sorted by time: "best" lap, all complete
+// "laps", hard "braking", "spin" from yaw rate and "impact" from ACCL
+func (s *Session) ClipEvents(cfg ClipConfig, kinds ...string) (events []ClipEvent, err error) {
+	for _, kind := range kinds {
+		var found []ClipEvent
+		switch kind {
+		case "best", "laps":
+			if s.Track == nil {
+				return nil, ErrUnknownTrack
+			}
+			if kind == "best" {
+				if best := s.Laps.Best(); best >= 0 && len(s.Laps.CompleteLaps()) > 0 {
+					found = append(found, s.lapEvent("best", best))
+				}
+				break
+			}
+			for _, lap := range s.Laps.CompleteLaps() {
+				found = append(found, s.lapEvent("lap", lap))
+			}
+		case "braking":
+			for _, z := range DetectZones(s.GPS, s.ACCL, 0, len(s.GPS)-1, DefaultZoneConfig) {
+				if z.Kind == Braking && -z.Peak >= cfg.BrakeThreshold {
+					found = append(found, ClipEvent{Kind: kind, Start: z.Start.Time, Stop: z.Stop.Time, Peak: -z.Peak})
+				}
+			}
+		case "spin":
+			series, yaw, yerr := s.yawRates(cfg.SpinMinSpeed)
+			if yerr != nil {
+				return nil, yerr
+			}
+			found = thresholdEvents(kind, series, yaw, cfg.SpinYawRate, cfg.Merge)
+		case "impact":
+			g := make([]float64, len(s.ACCL))
+			for i, a := range s.ACCL {
+				v := a.Value.(ACCL)
+				g[i] = math.Sqrt(v.X*v.X+v.Y*v.Y+v.Z*v.Z) / STANDARD_GRAVITY
+			}
+			found = thresholdEvents(kind, s.ACCL, g, cfg.ImpactG, cfg.Merge)
+		default:
+			return nil, fmt.Errorf("unknown event %s, available: %s", kind, strings.Join(CLIP_EVENTS, ", "))
+		}
+		events = append(events, found...)
+	}
+	if len(s.GPS) > 0 {
+		laps := s.Laps.lapOf(s.GPS)
+		for i, e := range events {
+			if e.Kind != "best" && e.Kind != "lap" {
+				if j := FindIndex(e.Start, s.GPS); j >= 0 {
+					events[i].Lap = laps[j]
+				} else {
+					events[i].Lap = -1
+				}
+			}
+		}
+	}
+	slices.SortStableFunc(events, func(a, b ClipEvent) int {
+		return a.Start.Compare(b.Start)
+	})
+	return
+}
+
+// ClipRange start and duration in video of event with pre and post roll,
+// video starts at first GPS sample like other frame tools
+func (s *Session) ClipRange(info VideoInfo, e ClipEvent, cfg ClipConfig) (start, duration time.Duration, err error) {
+	if len(s.GPS) == 0 {
+		return 0, 0, errors.New("no GPS to place clip in video")
+	}
+	start = max(0, e.Start.Sub(s.GPS[0].Time)-cfg.PreRoll)
+	stop := e.Stop.Sub(s.GPS[0].Time) + cfg.PostRoll
+	if info.Duration > 0 {
+		stop = min(stop, info.Duration)
+	}
+	if stop <= start {
+		return 0, 0, fmt.Errorf("%s is out of video", e)
+	}
+	return start, stop - start, nil
+}
+
+// ExtractClip cut duration of filename from start into out. When exact is false
+// video and audio are copied without loss, clip then begins at keyframe just
+// before start. Otherwise video is encoded again with codec and starts exactly.
+func ExtractClip(filename, out string, info VideoInfo, start, duration time.Duration, exact bool, codec string) (err error) {
+	in := ffmpeg.KwArgs{"ss": fmt.Sprintf("%.3f", start.Seconds())}
+	kw := ffmpeg.KwArgs{
+		"t":   fmt.Sprintf("%.3f", duration.Seconds()),
+		"map": []string{fmt.Sprintf("0:%d", info.Index)},
+		"c:v": "copy",
+		// timestamps from 0 when copy starts at a keyframe before start
+		"avoid_negative_ts": "make_zero",
+	}
+	if info.HasAudio {
+		kw["map"] = append(kw["map"].([]string), "0:a:0")
+		kw["c:a"] = "copy"
+	}
+	if exact {
+		kw["c:v"] = codec
+		kw["pix_fmt"] = "yuv420p"
+	}
+	if err = ffmpeg.Input(filename, in).Output(out, kw).OverWriteOutput().Run(); err != nil {
+		err = fmt.Errorf("extracting clip of %s:%s", filename, err)
+	}
+	return
+}
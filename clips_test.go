@@ -0,0 +1,68 @@
+package gokart
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestClipEvents(t *testing.T) {
+	s := circleSession([]float64{10, 12, 11})
+	// camera hit twice during second lap, then once more later
+	for i, g := range s.GPS {
+		a := ACCL{Z: STANDARD_GRAVITY}
+		if i == 900 || i == 903 || i == 1500 {
+			a.X = 5 * STANDARD_GRAVITY
+		}
+		s.ACCL = append(s.ACCL, Timely{Time: g.Time, Value: a})
+	}
+	events, err := s.ClipEvents(DefaultClipConfig, "best", "spin", "impact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("found %d events should be 3: %v", len(events), events)
+	}
+	best, impact := events[0], events[1]
+	if best.Kind != "best" || best.Lap != 2 || best.Stop.Sub(best.Start) != s.Laps.LapTime(2) {
+		t.Errorf("wrong best lap event %s", best)
+	}
+	if impact.Kind != "impact" || impact.Lap != 2 || impact.Stop.Sub(impact.Start) != 300*time.Millisecond {
+		t.Errorf("close impacts should be merged in %s", impact)
+	}
+	info := VideoInfo{Duration: s.GPS[len(s.GPS)-1].Time.Sub(s.GPS[0].Time)}
+	start, duration, err := s.ClipRange(info, events[2], DefaultClipConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 147*time.Second || duration != 5*time.Second {
+		t.Errorf("clip at %s for %s should be at 2m27s for 5s", start, duration)
+	}
+	// spin read from gyroscope while moving, jitter when stopped is ignored
+	for i, g := range s.GPS {
+		gyro := GYRO{Z: 0.1}
+		switch {
+		case i >= 300 && i < 310:
+			gyro.Z = -4
+		case i >= 1600 && i < 1610:
+			gyro.Z = float64(i%2*2-1) * 4
+			v := g.Value.(GPS5)
+			v.Speed3D = 0
+			s.GPS[i].Value = v
+		}
+		s.GYRO = append(s.GYRO, Timely{Time: g.Time, Value: gyro})
+	}
+	spins, err := s.ClipEvents(DefaultClipConfig, "spin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spins) != 1 || spins[0].Lap != 1 || math.Abs(spins[0].Peak-4) > 0.01 || spins[0].Stop.Sub(spins[0].Start) != 900*time.Millisecond {
+		t.Errorf("wrong spins %v", spins)
+	}
+	if _, err = s.LapClipEvent(4); err == nil {
+		t.Error("incomplete lap should fail")
+	}
+	if _, err = s.ClipEvents(DefaultClipConfig, "unknown"); err == nil {
+		t.Error("unknown event should fail")
+	}
+}
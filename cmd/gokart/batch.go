@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Serli/gokart"
+)
+
+func runBatch(args []string) (err error) {
+	fs := newFlagSet("batch")
+	dir := fs.String("dir", ".", "Directory tree of GoPro videos")
+	out := fs.String("out", "batch", "Output directory for best lap images and summary.json")
+	path := fs.String("path", ".", "Path for aerial images storage")
+	mode := fs.String("mode", gokart.DefaultBatchOptions.Mode, "Info drawn on best lap images")
+	workers := fs.Int("workers", 0, "Videos processed at the same time, number of CPU when 0")
+	noRender := fs.Bool("norender", false, "Do not render best lap images")
+	asJSON := fs.Bool("json", false, "JSON output of summary")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	files, err := gokart.FindVideos(*dir)
+	if err != nil {
+		return
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no video in %s", *dir)
+	}
+	opts := gokart.DefaultBatchOptions
+	opts.Workers = *workers
+	opts.Render = !*noRender
+	opts.Path = *path
+	opts.OutDir = *out
+	opts.Mode = *mode
+	if opts.Store, err = openStore(); err != nil {
+		return
+	}
+	opts.Progress = func(r gokart.BatchResult, done, total int) {
+		status := "ok"
+		if r.Error != "" {
+			status = "ERROR " + r.Error
+		}
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s %s in %s\n", done, total, r.File, status, r.Elapsed.Round(100*time.Millisecond))
+	}
+	results := gokart.RunBatch(files, opts)
+	if err = os.MkdirAll(*out, os.ModePerm); err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return
+	}
+	if err = os.WriteFile(filepath.Join(*out, "summary.json"), data, 0644); err != nil {
+		return
+	}
+	if *asJSON {
+		err = printJSON(os.Stdout, results)
+	} else {
+		err = gokart.WriteBatchSummary(os.Stdout, results)
+	}
+	if err == nil && gokart.BatchErrors(results) > 0 {
+		err = fmt.Errorf("%d of %d videos failed", gokart.BatchErrors(results), len(results))
+	}
+	return
+}
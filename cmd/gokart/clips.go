@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Serli/gokart"
+)
+
+// clipResult one extracted clip
+type clipResult struct {
+	Event gokart.ClipEvent `json:"event"`
+	File  string           `json:"file,omitempty"`
+	// Start and Duration in video, in seconds
+	Start    float64 `json:"start"`
+	Duration float64 `json:"duration"`
+}
+
+func runClips(args []string) (err error) {
+	cfg := gokart.DefaultClipConfig
+	fs := newFlagSet("clips")
+	in := fs.String("in", "", "Required: GoPro MP4 file to read")
+	outPath := fs.String("path", ".", "Path to store clips")
+	events := fs.String("events", "best", fmt.Sprintf("Comma separated events to cut: %s", strings.Join(gokart.CLIP_EVENTS, ", ")))
+	lap := fs.Int("lap", -1, "Also cut this lap")
+	pre := fs.Duration("pre", cfg.PreRoll, "Time kept before each event")
+	post := fs.Duration("post", cfg.PostRoll, "Time kept after each event")
+	brake := fs.Float64("brake", cfg.BrakeThreshold/gokart.STANDARD_GRAVITY, "Hard braking peak deceleration in g")
+	spin := fs.Float64("spin", cfg.SpinYawRate*180/math.Pi, "Spin yaw rate in °/s")
+	spinSpeed := fs.Float64("spinspeed", cfg.SpinMinSpeed*3.6, "Minimum speed of a spin in km/h")
+	impact := fs.Float64("impact", cfg.ImpactG, "Impact acceleration in g")
+	exact := fs.Bool("exact", false, "Encode again to start exactly at event, otherwise copied from keyframe before")
+	codec := fs.String("codec", "libx264", "Video codec with -exact")
+	list := fs.Bool("list", false, "Only list events, no clip")
+	asJSON := fs.Bool("json", false, "JSON output")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	if err = required(fs, "in", *in); err != nil {
+		return
+	}
+	cfg.PreRoll, cfg.PostRoll = *pre, *post
+	cfg.BrakeThreshold = *brake * gokart.STANDARD_GRAVITY
+	cfg.SpinYawRate = *spin * math.Pi / 180
+	cfg.SpinMinSpeed = *spinSpeed / 3.6
+	cfg.ImpactG = *impact
+	info, err := gokart.GetVideoInfo(*in)
+	if err != nil {
+		return
+	}
+	s, err := loadSession(*in)
+	if err != nil {
+		return
+	}
+	var kinds []string
+	if *events != "" {
+		kinds = strings.Split(*events, ",")
+	}
+	found, err := s.ClipEvents(cfg, kinds...)
+	if err != nil {
+		return
+	}
+	if *lap >= 0 {
+		e, lerr := s.LapClipEvent(*lap)
+		if lerr != nil {
+			return lerr
+		}
+		found = append(found, e)
+	}
+	if !*list {
+		if err = os.MkdirAll(*outPath, os.ModePerm); err != nil {
+			return
+		}
+	}
+	base := strings.TrimSuffix(filepath.Base(*in), filepath.Ext(*in))
+	results := make([]clipResult, 0, len(found))
+	for _, e := range found {
+		start, duration, rerr := s.ClipRange(info, e, cfg)
+		if rerr != nil {
+			return rerr
+		}
+		r := clipResult{Event: e, Start: start.Seconds(), Duration: duration.Seconds()}
+		if !*list {
+			r.File = filepath.Join(*outPath, fmt.Sprintf("%s_%s.mp4", base, e.Name()))
+			if err = gokart.ExtractClip(*in, r.File, info, start, duration, *exact, *codec); err != nil {
+				return
+			}
+		}
+		results = append(results, r)
+		if !*asJSON {
+			fmt.Printf("%s, video %s +%s %s\n", e, gokart.DurationToChrono(start),
+				duration.Round(time.Millisecond), r.File)
+		}
+	}
+	if *asJSON {
+		return printJSON(os.Stdout, results)
+	}
+	return
+}
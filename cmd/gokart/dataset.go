@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Serli/gokart"
+)
+
+// parseCrop x,y,width,height in pixels, empty for no crop
+func parseCrop(s string) (r image.Rectangle, err error) {
+	if s == "" {
+		return
+	}
+	var x, y, w, h int
+	if _, err = fmt.Sscanf(s, "%d,%d,%d,%d", &x, &y, &w, &h); err != nil {
+		return r, fmt.Errorf("invalid crop %q, expected x,y,width,height", s)
+	}
+	return image.Rect(x, y, x+w, y+h), nil
+}
+
+// parseSize widthxheight in pixels, 0 keeps aspect ratio, empty for no resize
+func parseSize(s string) (w, h int, err error) {
+	if s == "" {
+		return
+	}
+	if _, err = fmt.Sscanf(s, "%dx%d", &w, &h); err != nil || w < 0 || h < 0 {
+		return 0, 0, fmt.Errorf("invalid size %q, expected widthxheight", s)
+	}
+	return
+}
+
+func runDataset(args []string) (err error) {
+	fs := newFlagSet("dataset")
+	in := fs.String("in", "", "Required: GoPro MP4 file to read")
+	outPath := fs.String("path", "dataset", "Path to store manifest and train, val and test folders")
+	rate := fs.Float64("rate", gokart.DefaultDatasetConfig.Rate, "Frames per second kept, 0 for every frame")
+	val := fs.Float64("val", gokart.DefaultDatasetConfig.Val, "Fraction of laps in validation set")
+	test := fs.Float64("test", gokart.DefaultDatasetConfig.Test, "Fraction of laps in test set")
+	manifest := fs.String("manifest", "csv", fmt.Sprintf("Manifest format: %s", strings.Join(gokart.DATASET_FORMATS, " or ")))
+	crop := fs.String("crop", "", "Crop frames to x,y,width,height before resizing")
+	size := fs.String("size", "", "Resize frames to widthxheight, 0 keeps aspect ratio like 640x0")
+	labelsOnly := fs.Bool("labels", false, "Only write manifest, no image")
+	workers := fs.Int("workers", runtime.NumCPU(), "Images written in parallel")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	if err = required(fs, "in", *in); err != nil {
+		return
+	}
+	cfg := gokart.DatasetConfig{Rate: *rate, Val: *val, Test: *test}
+	cropRect, cerr := parseCrop(*crop)
+	width, height, serr := parseSize(*size)
+	for _, perr := range []error{cfg.Validate(), cerr, serr} {
+		if perr != nil {
+			fmt.Fprintln(fs.Output(), perr)
+			fs.Usage()
+			return errUsage
+		}
+	}
+	switch *manifest {
+	case "csv", "jsonl":
+	default:
+		fmt.Fprintf(fs.Output(), "unknown manifest format %s\n", *manifest)
+		fs.Usage()
+		return errUsage
+	}
+	info, err := gokart.GetVideoInfo(*in)
+	if err != nil {
+		return
+	}
+	s, err := loadSession(*in)
+	if err != nil && !errors.Is(err, gokart.ErrUnknownTrack) {
+		return
+	}
+	// frames are still labelled without track, lap is then always -1
+	labels, err := s.DatasetLabels(info, cfg)
+	if err != nil {
+		return
+	}
+	if len(labels) == 0 {
+		return fmt.Errorf("no frame with GPS in %s", *in)
+	}
+	if err = os.MkdirAll(*outPath, os.ModePerm); err != nil {
+		return
+	}
+	f, err := os.Create(filepath.Join(*outPath, "manifest."+*manifest))
+	if err != nil {
+		return
+	}
+	if err = gokart.WriteManifest(f, *manifest, labels); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	if *labelsOnly {
+		return
+	}
+	files := make(map[int]string, len(labels))
+	for _, l := range labels {
+		files[l.Frame] = filepath.Join(*outPath, filepath.FromSlash(l.File))
+	}
+	for _, split := range []string{"train", "val", "test"} {
+		if err = os.MkdirAll(filepath.Join(*outPath, split), os.ModePerm); err != nil {
+			return
+		}
+	}
+	frames := make([]int, 0, len(labels))
+	for _, l := range labels {
+		frames = append(frames, l.Frame)
+	}
+	err = gokart.ExtractFrames(*in, info, frames, *workers, func(count int, img *image.RGBA) (err error) {
+		out, err := gokart.PrepareFrame(img, cropRect, width, height)
+		if err != nil {
+			return
+		}
+		return writePNG(files[count], out)
+	})
+	if err != nil {
+		return
+	}
+	fmt.Printf("%d frames written in %s\n", len(frames), *outPath)
+	return
+}
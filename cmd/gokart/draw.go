@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Serli/gokart"
+)
+
+// writePNG encode img in filename
+func writePNG(filename string, img image.Image) (err error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return
+	}
+	if err = png.Encode(f, img); err != nil {
+		f.Close()
+		return
+	}
+	return f.Close()
+}
+
+func runDraw(args []string) (err error) {
+	fs := newFlagSet("draw")
+	in := fs.String("in", "", "Required: GoPro MP4 file to read")
+	out := fs.String("out", "best_lap.png", "Output lap image name")
+	lap := fs.Int("lap", 0, "Lap number to draw (0 for best)")
+	mode := fs.String("mode", "acc", fmt.Sprintf("Info to graph: %s, delta or line", strings.Join(gokart.Modes(), ", ")))
+	ref := fs.Int("ref", 0, "Reference lap for delta mode (0 for best)")
+	gears := fs.String("gears", "", "Shift speeds in km/h for gear mode, like 62,82,98,111,123 (default KZ shifter kart)")
+	lines := fs.Int("lines", 5, "Number of best laps averaged as racing line for line mode")
+	colors := fs.String("colors", "", "Color map: bgr, rwg, viridis, diverging or custom like #0000ff,#ff0000 (default depends on mode)")
+	scale := fs.String("scale", "", "Color scale: minmax, symmetric, p2 for 2-98% percentile or min:max (default depends on mode)")
+	path := fs.String("path", ".", "Path for aerial images storage")
+	minimap := fs.String("minimap", "", "Also write a mini-map of the lap with this name")
+	zones := fs.Bool("zones", false, "Print braking and throttle zones and draw them on map")
+	limits := fs.Bool("limits", false, "Print track limits excursions of lap and draw them on map")
+	asJSON := fs.Bool("json", false, "JSON output")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	if err = required(fs, "in", *in); err != nil {
+		return
+	}
+	s, err := loadSession(*in)
+	if err != nil {
+		return
+	}
+	lapnbr := s.Laps.Best()
+	if *lap != 0 {
+		lapnbr = *lap
+	}
+	var m gokart.Mode
+	switch *mode {
+	case "delta":
+		reference := s.Laps.Best()
+		if *ref != 0 {
+			reference = *ref
+		}
+		if m, err = s.Laps.DeltaMode(s.GPS, reference, lapnbr); err != nil {
+			return
+		}
+	case "gear":
+		m = gokart.GearMode(gokart.GEAR_SHIFTS...)
+		if *gears != "" {
+			var shifts []float64
+			for _, v := range strings.Split(*gears, ",") {
+				f, perr := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if perr != nil {
+					return fmt.Errorf("wrong shift speed %q:%w", v, perr)
+				}
+				shifts = append(shifts, f)
+			}
+			m = gokart.GearMode(shifts...)
+		}
+	case "line":
+		rl, rerr := s.Laps.RacingLine(s.GPS, s.Laps.BestLaps(*lines))
+		if rerr != nil {
+			return rerr
+		}
+		if m, err = s.Laps.DeviationMode(s.GPS, rl, lapnbr); err != nil {
+			return
+		}
+	default:
+		var ok bool
+		if m, ok = gokart.GetMode(*mode); !ok {
+			fmt.Fprintf(fs.Output(), "unknown mode %s\n", *mode)
+			fs.Usage()
+			return errUsage
+		}
+	}
+	if *colors != "" {
+		if m.Colors, err = gokart.ParseColorMap(*colors); err != nil {
+			return
+		}
+	}
+	if *scale != "" {
+		if m.Scale, err = gokart.ParseScale(*scale); err != nil {
+			return
+		}
+	}
+	rgba, err := s.Laps.DrawLapMode(*path, m, s.GPS, lapnbr)
+	if err != nil {
+		return
+	}
+	var found []gokart.Zone
+	if *zones {
+		if found, err = s.Laps.Zones(s.GPS, s.ACCL, lapnbr, gokart.DefaultZoneConfig); err != nil {
+			return
+		}
+		s.Track.DrawZones(rgba.(*image.RGBA), found)
+	}
+	var excursions []gokart.Excursion
+	if *limits {
+		r, lerr := s.TrackLimits(gokart.DefaultLimitsConfig)
+		if lerr != nil {
+			return lerr
+		}
+		for _, e := range r.Excursions {
+			if e.Lap == lapnbr {
+				excursions = append(excursions, e)
+			}
+		}
+		s.Track.DrawExcursions(rgba.(*image.RGBA), excursions)
+	}
+	if err = writePNG(*out, rgba); err != nil {
+		return
+	}
+	if *minimap != "" {
+		mm, merr := s.Laps.LapMiniMap(s.GPS, lapnbr, 320)
+		if merr != nil {
+			return merr
+		}
+		if err = writePNG(*minimap, mm.Image()); err != nil {
+			return
+		}
+	}
+	if *asJSON {
+		return printJSON(os.Stdout, struct {
+			Track      string             `json:"track"`
+			Lap        int                `json:"lap"`
+			Mode       string             `json:"mode"`
+			Out        string             `json:"out"`
+			Zones      []gokart.Zone      `json:"zones,omitempty"`
+			Excursions []gokart.Excursion `json:"excursions,omitempty"`
+		}{s.Track.Name, lapnbr, m.Name, *out, found, excursions})
+	}
+	fmt.Println("Track:", s.Track.Name)
+	fmt.Println("lap", lapnbr, "drawn in", *out)
+	for _, z := range found {
+		fmt.Println(z)
+	}
+	for _, e := range excursions {
+		fmt.Println(e)
+	}
+	return
+}
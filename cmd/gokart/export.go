@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Serli/gokart"
+)
+
+func runExport(args []string) (err error) {
+	fs := newFlagSet("export")
+	in := fs.String("in", "", "Required: GoPro MP4 file to read")
+	out := fs.String("out", "", "Output file, standard output when empty")
+	format := fs.String("format", "csv", "Output format: csv or gpx")
+	channels := fs.String("channels", "", fmt.Sprintf("Comma separated channels added to csv: %s", strings.Join(gokart.ChannelNames(), ", ")))
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	if err = required(fs, "in", *in); err != nil {
+		return
+	}
+	var write func(s *gokart.Session, w io.Writer) error
+	switch *format {
+	case "csv":
+		write = func(s *gokart.Session, w io.Writer) error {
+			if *channels == "" {
+				return s.WriteCSV(w)
+			}
+			return s.WriteCSV(w, strings.Split(*channels, ",")...)
+		}
+	case "gpx":
+		write = (*gokart.Session).WriteGPX
+	default:
+		fmt.Fprintf(fs.Output(), "unknown format %s\n", *format)
+		fs.Usage()
+		return errUsage
+	}
+	s, err := loadSession(*in)
+	if err != nil && !errors.Is(err, gokart.ErrUnknownTrack) {
+		return
+	}
+	// samples are still exported without track, lap is then always -1
+	if *out == "" {
+		return write(s, os.Stdout)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return
+	}
+	if err = write(s, f); err != nil {
+		f.Close()
+		return
+	}
+	return f.Close()
+}
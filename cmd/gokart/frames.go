@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Serli/gokart"
+)
+
+// framePos GPS position of one frame, frame numbers start at 1
+type framePos struct {
+	Frame int                `json:"frame"`
+	GPS   gokart.GPS5        `json:"gps"`
+	Event *gokart.FrameEvent `json:"event,omitempty"`
+}
+
+func runFrames(args []string) (err error) {
+	fs := newFlagSet("frames")
+	in := fs.String("in", "", "Required: GoPro MP4 file to read")
+	outPath := fs.String("path", ".", "Path to store generated files")
+	start := fs.Int("start", 1, "Start frame number")
+	stop := fs.Int("stop", -1, "Stop frame number, -1 for last")
+	from := fs.Duration("from", 0, "Start time from video start like 1m30s, instead of -start")
+	stride := fs.Int("stride", 1, "Keep one frame every stride frames")
+	events := fs.String("events", "", fmt.Sprintf("Only frames of comma separated events: %s", strings.Join(gokart.FRAME_EVENTS, ", ")))
+	workers := fs.Int("workers", runtime.NumCPU(), "Images written in parallel")
+	export := fs.Bool("export", false, "Export each image as frame_{number}.png WARNING can fill your drive!!!!")
+	asJSON := fs.Bool("json", false, "JSON lines output, one per frame")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	if err = required(fs, "in", *in); err != nil {
+		return
+	}
+	if *start < 1 || (*stop != -1 && *stop < *start) || *stride < 1 || *from < 0 {
+		fmt.Fprintln(fs.Output(), "start must be >= 1, stop >= start, stride >= 1 and from >= 0")
+		fs.Usage()
+		return errUsage
+	}
+	info, err := gokart.GetVideoInfo(*in)
+	if err != nil {
+		return
+	}
+	s, err := loadSession(*in)
+	if err != nil && (*events != "" || !errors.Is(err, gokart.ErrUnknownTrack)) {
+		return
+	}
+	if len(s.GPS) == 0 {
+		return fmt.Errorf("no GPS point in %s", *in)
+	}
+	var frames []int
+	byFrame := make(map[int]*gokart.FrameEvent)
+	if *events != "" {
+		evs, eerr := s.FrameEvents(strings.Split(*events, ",")...)
+		if eerr != nil {
+			return eerr
+		}
+		for i, e := range evs {
+			n := s.FrameOf(info, e.Time)
+			frames = append(frames, n)
+			byFrame[n] = &evs[i]
+		}
+	} else {
+		first, last := *start, *stop
+		if *from > 0 {
+			first = info.FrameAt(*from) + 1
+		}
+		if last == -1 {
+			last = info.Frames
+			if last == 0 {
+				last = info.FrameAt(info.Duration)
+			}
+		}
+		frames = gokart.StrideFrames(first, last, *stride)
+	}
+	// "best" video starting point time
+	startTime := s.GPS[0].Time
+	positions := make(map[int]gokart.GPS5, len(frames))
+	enc := json.NewEncoder(os.Stdout)
+	for _, count := range frames {
+		inter, ierr := gokart.InterpolateAt(startTime.Add(info.FrameTime(count-1)), s.GPS)
+		if ierr != nil {
+			// no GPS for this frame
+			continue
+		}
+		pos := inter.Value.(gokart.GPS5)
+		positions[count] = pos
+		if *asJSON {
+			if err = enc.Encode(framePos{count, pos, byFrame[count]}); err != nil {
+				return
+			}
+		} else {
+			fmt.Printf(
+				"frame %d latitude:%f longitude:%f accuracy (in cm):%d\n",
+				count, pos.Latitude, pos.Longitude, pos.Accuracy)
+		}
+	}
+	if !*export {
+		return
+	}
+	begin := time.Now()
+	wanted := make([]int, 0, len(positions))
+	for count := range positions {
+		wanted = append(wanted, count)
+	}
+	err = gokart.ExtractFrames(*in, info, wanted, *workers, func(count int, img *image.RGBA) (err error) {
+		path := filepath.Join(*outPath, fmt.Sprintf("part%03d", count/1000))
+		if err = os.MkdirAll(path, os.ModePerm); err != nil {
+			return
+		}
+		if err = writePNG(filepath.Join(path, fmt.Sprintf("frame_%d.png", count)), img); err != nil {
+			return
+		}
+		data, err := json.MarshalIndent(positions[count], "", "  ")
+		if err != nil {
+			return
+		}
+		return os.WriteFile(filepath.Join(path, fmt.Sprintf("frame_%d.json", count)), data, 0644)
+	})
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%d frames exported in %s\n", len(wanted), time.Since(begin).Round(time.Millisecond))
+	return
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Serli/gokart"
+)
+
+// lapJSON one lap in laps json output, durations in milliseconds
+type lapJSON struct {
+	Lap     int     `json:"lap"`
+	Start   string  `json:"start"`
+	Time    int64   `json:"time"`
+	Error   int64   `json:"error"`
+	Sectors []int64 `json:"sectors"`
+	Best    bool    `json:"best"`
+}
+
+func runLaps(args []string) (err error) {
+	fs := newFlagSet("laps")
+	in := fs.String("in", "", "Required: GoPro MP4 file to read")
+	asJSON := fs.Bool("json", false, "JSON output")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	if err = required(fs, "in", *in); err != nil {
+		return
+	}
+	s, err := loadSession(*in)
+	if err != nil {
+		return
+	}
+	laps := s.Laps.CompleteLaps()
+	best := s.Laps.Best()
+	if *asJSON {
+		out := struct {
+			Track       string          `json:"track"`
+			Meta        gokart.Metadata `json:"meta"`
+			Laps        []lapJSON       `json:"laps"`
+			Best        int             `json:"best"`
+			Theoretical int64           `json:"theoretical"`
+		}{
+			Track:       s.Track.Name,
+			Meta:        s.Meta,
+			Laps:        make([]lapJSON, 0, len(laps)),
+			Best:        best,
+			Theoretical: s.Laps.TheroreticalBest().Milliseconds(),
+		}
+		for _, lap := range laps {
+			l := lapJSON{
+				Lap:   lap,
+				Start: s.Laps.LapStart(lap).Format(time.RFC3339Nano),
+				Time:  s.Laps.LapTime(lap).Milliseconds(),
+				Error: s.Laps.LapError(lap).Milliseconds(),
+				Best:  lap == best,
+			}
+			for _, d := range s.Laps.SectorTimes(lap) {
+				l.Sectors = append(l.Sectors, d.Milliseconds())
+			}
+			out.Laps = append(out.Laps, l)
+		}
+		return printJSON(os.Stdout, out)
+	}
+	fmt.Println("Track:", s.Track.Name)
+	if s.Meta.Driver != "" {
+		fmt.Println("Driver:", s.Meta.Driver)
+	}
+	if desc := s.Meta.Description(); desc != "" {
+		fmt.Println("Setup:", desc)
+	}
+	for _, lap := range laps {
+		var b strings.Builder
+		fmt.Fprintf(&b, "Lap %02d %s", lap, gokart.DurationToChrono(s.Laps.LapTime(lap)))
+		for i, d := range s.Laps.SectorTimes(lap) {
+			fmt.Fprintf(&b, " S%02d %s", i+1, gokart.DurationToChrono(d))
+		}
+		if lap == best {
+			b.WriteString(" best")
+		}
+		fmt.Println(b.String())
+	}
+	if len(laps) == 0 {
+		fmt.Println("no complete lap")
+		return
+	}
+	fmt.Println("Theoretical best", gokart.DurationToChrono(s.Laps.TheroreticalBest()))
+	return
+}
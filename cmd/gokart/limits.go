@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Serli/gokart"
+)
+
+func runLimits(args []string) (err error) {
+	fs := newFlagSet("limits")
+	in := fs.String("in", "", "Required: GoPro MP4 file to read")
+	tolerance := fs.Float64("tolerance", gokart.DefaultLimitsConfig.Tolerance, "Metres beyond track edge allowed")
+	duration := fs.Duration("duration", gokart.DefaultLimitsConfig.MinDuration, "Shorter excursions are ignored")
+	maxExcursions := fs.Int("max", gokart.DefaultLimitsConfig.MaxExcursions, "Excursions allowed per lap before it is invalidated")
+	asJSON := fs.Bool("json", false, "JSON output")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	if err = required(fs, "in", *in); err != nil {
+		return
+	}
+	s, err := loadSession(*in)
+	if err != nil {
+		return
+	}
+	r, err := s.TrackLimits(gokart.LimitsConfig{
+		Tolerance:     *tolerance,
+		MinDuration:   *duration,
+		MaxExcursions: *maxExcursions,
+	})
+	if err != nil {
+		return fmt.Errorf("%s:%w", s.Track.Name, err)
+	}
+	if *asJSON {
+		return printJSON(os.Stdout, struct {
+			Track string `json:"track"`
+			gokart.LimitsReport
+		}{s.Track.Name, r})
+	}
+	fmt.Println("Track:", s.Track.Name)
+	for _, e := range r.Excursions {
+		fmt.Println(e)
+	}
+	if len(r.Invalidated) == 0 {
+		fmt.Println("no invalidated lap")
+		return
+	}
+	laps := make([]string, len(r.Invalidated))
+	for i, lap := range r.Invalidated {
+		laps[i] = strconv.Itoa(lap)
+	}
+	fmt.Println("invalidated laps:", strings.Join(laps, ", "))
+	return
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Serli/gokart"
+)
+
+func runLive(args []string) (err error) {
+	fs := newFlagSet("live")
+	udp := fs.String("udp", "", "Read NMEA sentences from UDP address like :10110")
+	serial := fs.String("serial", "", "Read NMEA sentences from serial device like /dev/ttyUSB0, configured before with stty")
+	replay := fs.String("replay", "", "Replay a GoPro MP4, NMEA or CSV file")
+	speed := fs.Float64("speed", 1, "Replay speed, 0 as fast as possible")
+	trackName := fs.String("track", "", "Track name, found from first precise position when empty")
+	addr := fs.String("addr", "", "Publish events on WebSocket ws://addr/live, like localhost:8081")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	sources := 0
+	for _, s := range []string{*udp, *serial, *replay} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		fmt.Fprintln(fs.Output(), "exactly one of -udp, -serial or -replay is required")
+		fs.Usage()
+		return errUsage
+	}
+	var track *gokart.Track
+	if *trackName != "" {
+		if track = gokart.TheWorld.TrackByName(*trackName); track == nil {
+			return fmt.Errorf("unknown track %s", *trackName)
+		}
+	}
+	var hub *gokart.LiveHub
+	if *addr != "" {
+		hub = gokart.NewLiveHub()
+		mux := http.NewServeMux()
+		mux.Handle("GET /live", hub)
+		ln, lerr := net.Listen("tcp", *addr)
+		if lerr != nil {
+			return lerr
+		}
+		fmt.Fprintf(os.Stderr, "publishing on ws://%s/live\n", ln.Addr())
+		go http.Serve(ln, mux)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	var timer *gokart.LiveTimer
+	push := func(g gokart.Timely) (err error) {
+		if timer == nil {
+			if track == nil {
+				if g.Value.(gokart.GPS5).Accuracy >= 10000 {
+					// not precise enough to find track
+					return
+				}
+				if track = gokart.TheWorld.GetTrack([]gokart.Timely{g}); track == nil {
+					return gokart.ErrUnknownTrack
+				}
+				fmt.Fprintf(os.Stderr, "track %s\n", track.Name)
+			}
+			timer = gokart.NewLiveTimer(track)
+		}
+		for _, e := range timer.Push(g) {
+			if err = enc.Encode(e); err != nil {
+				return
+			}
+			if hub != nil {
+				if err = hub.Publish(e); err != nil {
+					return
+				}
+			}
+		}
+		return
+	}
+	switch {
+	case *udp != "":
+		conn, lerr := net.ListenPacket("udp", *udp)
+		if lerr != nil {
+			return lerr
+		}
+		defer conn.Close()
+		// one or more sentences per datagram
+		return gokart.ReadNMEAStream(conn.(io.Reader), push)
+	case *serial != "":
+		f, oerr := os.Open(*serial)
+		if oerr != nil {
+			return oerr
+		}
+		defer f.Close()
+		return gokart.ReadNMEAStream(f, push)
+	}
+	paced := gokart.Paced(*speed, push)
+	if ext := strings.ToLower(filepath.Ext(*replay)); ext != ".nmea" && ext != ".nma" {
+		// videos and CSV logs are read at once
+		s, lerr := loadSession(*replay)
+		if lerr != nil && !errors.Is(lerr, gokart.ErrUnknownTrack) {
+			return lerr
+		}
+		for _, g := range s.GPS {
+			if err = paced(g); err != nil {
+				return
+			}
+		}
+		return
+	}
+	f, err := os.Open(*replay)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	return gokart.ReadNMEAStream(f, paced)
+}
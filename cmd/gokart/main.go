@@ -0,0 +1,153 @@
+// gokart command line, one subcommand per feature:
+//
+//	gokart <command> [flags]
+//
+// Exit code is 0 on success, 1 on error and 2 on wrong usage.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/Serli/gokart"
+)
+
+// errUsage wrong command line, usage already printed
+var errUsage = errors.New("wrong usage")
+
+// command one gokart subcommand
+type command struct {
+	short string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"probe":   {"show streams and codecs of a video", runProbe},
+	"report":  {"write an HTML session report", runReport},
+	"serve":   {"local web viewer and JSON API", runServe},
+	"store":   {"add and query cached sessions", runStore},
+	"laps":    {"print lap and sector times", runLaps},
+	"limits":  {"detect track limits violations", runLimits},
+	"batch":   {"process all videos of a directory tree", runBatch},
+	"draw":    {"draw a lap on track aerial image", runDraw},
+	"frames":  {"export frames with their GPS position", runFrames},
+	"dataset": {"export labelled frames for machine learning", runDataset},
+	"clips":   {"cut video clips around laps and incidents", runClips},
+	"live":    {"live lap timer from NMEA stream or replay", runLive},
+	"export":  {"export GPS samples as CSV or GPX", runExport},
+	"tracks":  {"list and validate known tracks", runTracks},
+	"overlay": {"render telemetry overlay on video", runOverlay},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: gokart <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", name, commands[name].short)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'gokart <command> -h' for command flags.")
+}
+
+// newFlagSet flags of a subcommand, errors are returned not fatal
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gokart %s [flags]\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse flags, help or wrong flags are usage errors
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return errUsage
+	}
+	return nil
+}
+
+// required mandatory string flag
+func required(fs *flag.FlagSet, name, value string) error {
+	if value == "" {
+		fmt.Fprintf(fs.Output(), "flag -%s is required\n", name)
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// printJSON indented json on w
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// STORE_ENV environment variable with session store directory,
+// telemetry is parsed at each run when not set
+const STORE_ENV = "GOKART_STORE"
+
+// openStore session store from environment, nil when not set
+func openStore() (st *gokart.Store, err error) {
+	if dir := os.Getenv(STORE_ENV); dir != "" {
+		return gokart.OpenStore(dir)
+	}
+	return
+}
+
+// loadSession session with a known track, from store when available
+func loadSession(filename string) (s *gokart.Session, err error) {
+	st, err := openStore()
+	if err != nil {
+		return
+	}
+	if st != nil {
+		s, err = st.Load(filename)
+	} else {
+		s, err = gokart.LoadSession(filename)
+	}
+	if err != nil {
+		err = fmt.Errorf("%s:%w", filename, err)
+	}
+	return
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "-h" || name == "-help" || name == "--help" || name == "help" {
+		usage()
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "gokart: unknown command %q\n", name)
+		usage()
+		os.Exit(2)
+	}
+	err := cmd.run(os.Args[2:])
+	switch {
+	case err == nil, err == flag.ErrHelp:
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "gokart %s: %s\n", name, err)
+		os.Exit(1)
+	}
+}
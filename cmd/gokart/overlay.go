@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Serli/gokart"
+)
+
+func runOverlay(args []string) (err error) {
+	fs := newFlagSet("overlay")
+	in := fs.String("in", "", "Required: GoPro MP4 file to read")
+	out := fs.String("out", "overlay.mp4", "Output video name")
+	path := fs.String("path", "", "Path for logos storage, logo paths from track are used when empty")
+	start := fs.Duration("start", 0, "Start rendering at this position in video (e.g. 1m30s)")
+	duration := fs.Duration("duration", 0, "Rendered duration, 0 for whole video")
+	noLogo := fs.Bool("nologo", false, "Do not draw track logo")
+	noMap := fs.Bool("nomap", false, "Do not draw mini-map")
+	codec := fs.String("codec", gokart.DefaultOverlayOptions.Codec, "Output video codec")
+	crf := fs.Int("crf", gokart.DefaultOverlayOptions.CRF, "Output constant rate factor, lower is better")
+	asJSON := fs.Bool("json", false, "JSON output")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	if err = required(fs, "in", *in); err != nil {
+		return
+	}
+	opts := gokart.DefaultOverlayOptions
+	opts.Path = *path
+	opts.Start = *start
+	opts.Duration = *duration
+	opts.Logo = !*noLogo
+	opts.MiniMap = !*noMap
+	opts.Codec = *codec
+	opts.CRF = *crf
+	begin := time.Now()
+	if err = gokart.RenderOverlay(*in, *out, opts); err != nil {
+		return
+	}
+	if *asJSON {
+		return printJSON(os.Stdout, struct {
+			Out     string  `json:"out"`
+			Seconds float64 `json:"seconds"`
+		}{*out, time.Since(begin).Seconds()})
+	}
+	fmt.Println(*out, "rendered in", time.Since(begin))
+	return
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/Serli/gokart"
+)
+
+func runProbe(args []string) (err error) {
+	fs := newFlagSet("probe")
+	in := fs.String("in", "", "Required: video file to probe")
+	asJSON := fs.Bool("json", false, "JSON output")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	if err = required(fs, "in", *in); err != nil {
+		return
+	}
+	streams, err := gokart.GetStreamsCodecTag(*in)
+	if err != nil {
+		return
+	}
+	info, err := gokart.GetVideoInfo(*in)
+	if err != nil {
+		return
+	}
+	// not a GoPro video or older camera, quirks are then defaults
+	camera, _ := gokart.ReadCamera(*in)
+	quirks := gokart.QuirksFor(camera)
+	if gpmd, gerr := gokart.ReadGoProStream(*in); gerr == nil {
+		quirks.IMU = gokart.ReadOrientations(gpmd)["ACCL"]
+	}
+	if *asJSON {
+		return printJSON(os.Stdout, struct {
+			Streams map[int]string   `json:"streams"`
+			Video   gokart.VideoInfo `json:"video"`
+			Camera  gokart.Camera    `json:"camera"`
+			Quirks  gokart.Quirks    `json:"quirks"`
+		}{streams, info, camera, quirks})
+	}
+	indexes := make([]int, 0, len(streams))
+	for index := range streams {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		fmt.Printf("stream %d %s\n", index, streams[index])
+	}
+	fmt.Printf("video %s %dx%d %.3f fps %d frames %s audio:%v\n",
+		info.Codec, info.Width, info.Height, info.FrameRate(), info.Frames, info.Duration, info.HasAudio)
+	fmt.Printf("camera %q serial %q firmware %q\n", camera.Model, camera.Serial, camera.Firmware)
+	gps := quirks.GPS
+	if gps == "" {
+		gps = "none"
+	}
+	imu := string(quirks.IMU)
+	if imu == "" {
+		imu = "as stored"
+	}
+	fmt.Printf("quirks HERO%d gps %s imu %s\n", quirks.Generation, gps, imu)
+	return
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Serli/gokart"
+)
+
+func runReport(args []string) (err error) {
+	fs := newFlagSet("report")
+	in := fs.String("in", "", "Required: GoPro MP4 file to read")
+	out := fs.String("out", "report.html", "Output HTML file")
+	path := fs.String("path", gokart.DefaultReportOptions.Path, "Path for aerial images storage")
+	mode := fs.String("mode", gokart.DefaultReportOptions.Mode, "Info drawn on best lap map")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	if err = required(fs, "in", *in); err != nil {
+		return
+	}
+	s, err := loadSession(*in)
+	if err != nil {
+		return
+	}
+	opts := gokart.DefaultReportOptions
+	opts.Path = *path
+	opts.Mode = *mode
+	f, err := os.Create(*out)
+	if err != nil {
+		return
+	}
+	if err = s.WriteReport(f, opts); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	fmt.Println("report written in", *out)
+	return
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Serli/gokart"
+)
+
+func runServe(args []string) (err error) {
+	fs := newFlagSet("serve")
+	dir := fs.String("dir", ".", "Directory of GoPro videos")
+	addr := fs.String("addr", "localhost:8080", "Listen address")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	server := gokart.NewServer(*dir)
+	if server.Store, err = openStore(); err != nil {
+		return
+	}
+	fmt.Printf("serving %s on http://%s\n", *dir, *addr)
+	return http.ListenAndServe(*addr, server)
+}
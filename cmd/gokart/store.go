@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Serli/gokart"
+)
+
+const storeUsage = `Actions:
+  add FILE...          parse and store sessions
+  list                 stored sessions
+  laps                 all laps
+  best                 best lap per track and driver
+  history              best lap of each session over time
+  driver HASH NAME     set driver of a session
+  remove HASH          remove a session`
+
+// printLaps lap records as table
+func printLaps(laps []gokart.LapRecord) {
+	for _, l := range laps {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s %-16s %-12s lap %02d %s", l.Start.Format("2006-01-02 15:04"), l.Track, l.Driver, l.Lap, gokart.DurationToChrono(l.Time))
+		for i, d := range l.Sectors {
+			fmt.Fprintf(&b, " S%02d %s", i+1, gokart.DurationToChrono(d))
+		}
+		fmt.Fprintf(&b, " %s", l.Hash[:min(12, len(l.Hash))])
+		fmt.Println(b.String())
+	}
+}
+
+func runStore(args []string) (err error) {
+	fs := newFlagSet("store")
+	dir := fs.String("dir", os.Getenv(STORE_ENV), "Store directory, "+STORE_ENV+" environment variable by default")
+	track := fs.String("track", "", "Only laps of this track")
+	driver := fs.String("driver", "", "Only laps of this driver")
+	from := fs.String("from", "", "Only laps after this date (2006-01-02)")
+	to := fs.String("to", "", "Only laps before this date (2006-01-02)")
+	asJSON := fs.Bool("json", false, "JSON output")
+	usage := fs.Usage
+	fs.Usage = func() {
+		usage()
+		fmt.Fprintln(fs.Output(), storeUsage)
+	}
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	if err = required(fs, "dir", *dir); err != nil {
+		return
+	}
+	rest := fs.Args()
+	if len(rest) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	q := gokart.LapQuery{Track: *track, Driver: *driver}
+	for _, d := range []struct {
+		value string
+		t     *time.Time
+	}{{*from, &q.From}, {*to, &q.To}} {
+		if d.value == "" {
+			continue
+		}
+		if *d.t, err = time.ParseInLocation("2006-01-02", d.value, time.Local); err != nil {
+			fmt.Fprintln(fs.Output(), err)
+			return errUsage
+		}
+	}
+	st, err := gokart.OpenStore(*dir)
+	if err != nil {
+		return
+	}
+	action, params := rest[0], rest[1:]
+	// number of parameters of each action, -1 for at least one
+	counts := map[string]int{"add": -1, "list": 0, "laps": 0, "best": 0, "history": 0, "driver": 2, "remove": 1}
+	count, ok := counts[action]
+	if !ok || (count == -1 && len(params) == 0) || (count >= 0 && len(params) != count) {
+		fs.Usage()
+		return errUsage
+	}
+	var laps []gokart.LapRecord
+	switch action {
+	case "add":
+		for _, filename := range params {
+			s, lerr := st.Load(filename)
+			if lerr != nil && !errors.Is(lerr, gokart.ErrUnknownTrack) {
+				// keep adding other files
+				fmt.Fprintf(os.Stderr, "%s:%s\n", filename, lerr)
+				err = fmt.Errorf("some sessions were not added")
+				continue
+			}
+			if s.Track == nil {
+				fmt.Printf("%s unknown track\n", filename)
+				continue
+			}
+			fmt.Printf("%s %s %d laps\n", filename, s.Track.Name, len(s.Laps.CompleteLaps()))
+		}
+		return
+	case "list":
+		entries := st.Entries()
+		if *asJSON {
+			return printJSON(os.Stdout, entries)
+		}
+		for _, e := range entries {
+			fmt.Printf("%s %s %-16s %-12s %2d laps %s\n", e.Hash[:12], e.Start.Format("2006-01-02 15:04"), e.Track, e.Driver, len(e.Laps), e.File)
+		}
+		return
+	case "driver", "remove":
+		hash, err := findHash(st, params[0])
+		if err != nil {
+			return err
+		}
+		if action == "driver" {
+			return st.SetDriver(hash, params[1])
+		}
+		return st.Remove(hash)
+	case "laps":
+		laps = st.Laps(q)
+	case "best":
+		laps = st.BestLaps(q)
+	case "history":
+		laps = st.History(q)
+	}
+	if *asJSON {
+		return printJSON(os.Stdout, laps)
+	}
+	printLaps(laps)
+	return
+}
+
+// findHash full hash from an unambiguous prefix
+func findHash(st *gokart.Store, prefix string) (hash string, err error) {
+	for _, e := range st.Entries() {
+		if !strings.HasPrefix(e.Hash, prefix) {
+			continue
+		}
+		if hash != "" {
+			return "", fmt.Errorf("ambiguous session %s", prefix)
+		}
+		hash = e.Hash
+	}
+	if hash == "" {
+		err = fmt.Errorf("unknown session %s", prefix)
+	}
+	return
+}
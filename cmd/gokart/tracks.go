@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Serli/gokart"
+)
+
+// trackJSON one track in tracks json output
+type trackJSON struct {
+	Name    string   `json:"name"`
+	Sectors int      `json:"sectors"`
+	Valid   bool     `json:"valid"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+func runTracks(args []string) (err error) {
+	fs := newFlagSet("tracks")
+	world := fs.String("world", "", "World json file to check, embedded theworld.json when empty")
+	asJSON := fs.Bool("json", false, "JSON output")
+	if err = parse(fs, args); err != nil {
+		return
+	}
+	w := gokart.TheWorld
+	if *world != "" {
+		if w, err = gokart.LoadWorld(*world); err != nil {
+			return
+		}
+	}
+	tracks := make([]trackJSON, 0, len(w.Tracks))
+	invalid := 0
+	names := make(map[string]bool)
+	for _, t := range w.Tracks {
+		tj := trackJSON{Name: t.Name, Sectors: len(t.Sectors), Valid: true}
+		if verr := t.Validate(); verr != nil {
+			// Validate joins all problems
+			if joined, ok := verr.(interface{ Unwrap() []error }); ok {
+				for _, e := range joined.Unwrap() {
+					tj.Errors = append(tj.Errors, e.Error())
+				}
+			} else {
+				tj.Errors = append(tj.Errors, verr.Error())
+			}
+		}
+		if names[t.Name] {
+			tj.Errors = append(tj.Errors, "duplicated name")
+		}
+		names[t.Name] = true
+		if len(tj.Errors) > 0 {
+			tj.Valid = false
+			invalid++
+		}
+		tracks = append(tracks, tj)
+	}
+	if *asJSON {
+		err = printJSON(os.Stdout, tracks)
+	} else {
+		for _, t := range tracks {
+			status := "ok"
+			if !t.Valid {
+				status = "INVALID"
+			}
+			fmt.Printf("%-20s %d sectors %s\n", t.Name, t.Sectors, status)
+			for _, e := range t.Errors {
+				fmt.Println("  ", e)
+			}
+		}
+	}
+	if err == nil && invalid > 0 {
+		err = fmt.Errorf("%d invalid track(s)", invalid)
+	}
+	return
+}
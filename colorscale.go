@@ -0,0 +1,405 @@
+package gokart
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ColorStop color at a position in [0 1]
+type ColorStop struct {
+	Pos   float64
+	Color color.RGBA
+}
+
+// ColorMap colors linearly interpolated between stops sorted by position
+type ColorMap []ColorStop
+
+// NewColorMap evenly spaced stops from given colors
+func NewColorMap(colors ...color.RGBA) (m ColorMap) {
+	m = make(ColorMap, len(colors))
+	for i, c := range colors {
+		m[i] = ColorStop{Pos: float64(i) / float64(max(1, len(colors)-1)), Color: c}
+	}
+	return
+}
+
+// At color for ratio in [0 1], ratio is clamped, unknown NaN ratio is grey
+func (m ColorMap) At(ratio float64) color.RGBA {
+	if len(m) == 0 {
+		return white
+	}
+	if math.IsNaN(ratio) {
+		return grey
+	}
+	if ratio <= m[0].Pos {
+		return m[0].Color
+	}
+	for i := 1; i < len(m); i++ {
+		if ratio > m[i].Pos {
+			continue
+		}
+		w := (ratio - m[i-1].Pos) / (m[i].Pos - m[i-1].Pos)
+		a, b := m[i-1].Color, m[i].Color
+		return color.RGBA{
+			uint8((1-w)*float64(a.R) + w*float64(b.R) + 0.5),
+			uint8((1-w)*float64(a.G) + w*float64(b.G) + 0.5),
+			uint8((1-w)*float64(a.B) + w*float64(b.B) + 0.5),
+			255,
+		}
+	}
+	return m[len(m)-1].Color
+}
+
+var (
+	// BlueGreenRed historical speed colors
+	BlueGreenRed = NewColorMap(blue, green, red)
+	// RedWhiteGreen historical acceleration colors, deceleration in red
+	RedWhiteGreen = NewColorMap(red, white, green)
+	// Viridis perceptually uniform, readable by color blind people
+	Viridis = NewColorMap(
+		color.RGBA{68, 1, 84, 255},
+		color.RGBA{59, 82, 139, 255},
+		color.RGBA{33, 145, 140, 255},
+		color.RGBA{94, 201, 98, 255},
+		color.RGBA{253, 231, 37, 255},
+	)
+	// Diverging blue for negative, white around zero, red for positive
+	Diverging = NewColorMap(
+		color.RGBA{33, 102, 172, 255},
+		color.RGBA{103, 169, 207, 255},
+		color.RGBA{247, 247, 247, 255},
+		color.RGBA{239, 138, 98, 255},
+		color.RGBA{178, 24, 43, 255},
+	)
+)
+
+// ColorMaps available by name, used by command line
+var ColorMaps = map[string]ColorMap{
+	"bgr":       BlueGreenRed,
+	"rwg":       RedWhiteGreen,
+	"viridis":   Viridis,
+	"diverging": Diverging,
+}
+
+// ParseColorMap a named color map or custom colors as comma separated hex values
+// like "#0000ff,#ffffff,#ff0000"
+func ParseColorMap(s string) (m ColorMap, err error) {
+	if m, ok := ColorMaps[s]; ok {
+		return m, nil
+	}
+	colors := make([]color.RGBA, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimPrefix(strings.TrimSpace(part), "#")
+		v, perr := strconv.ParseUint(part, 16, 32)
+		if perr != nil || len(part) != 6 {
+			err = fmt.Errorf("unknown color map or wrong color %q", part)
+			return
+		}
+		colors = append(colors, color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255})
+	}
+	if len(colors) < 2 {
+		err = fmt.Errorf("at least 2 colors needed in %q", s)
+		return
+	}
+	m = NewColorMap(colors...)
+	return
+}
+
+// ScaleKind how value range is computed
+type ScaleKind int
+
+const (
+	// ScaleMinMax from minimum to maximum value
+	ScaleMinMax ScaleKind = iota
+	// ScaleSymmetric zero is centered, from -max(|v|) to max(|v|)
+	ScaleSymmetric
+	// ScalePercentile ignore extreme values, from Percentile to 100-Percentile
+	ScalePercentile
+	// ScaleFixed from Min to Max
+	ScaleFixed
+)
+
+// Scale map values to [0 1]
+type Scale struct {
+	Kind ScaleKind
+	// Percentile in % of values ignored at each end for ScalePercentile
+	Percentile float64
+	// Min and Max for ScaleFixed
+	Min float64
+	Max float64
+}
+
+// ParseScale "minmax", "symmetric", "pN" for N% percentile or "min:max"
+func ParseScale(s string) (scale Scale, err error) {
+	switch {
+	case s == "minmax":
+		scale.Kind = ScaleMinMax
+	case s == "symmetric":
+		scale.Kind = ScaleSymmetric
+	case strings.HasPrefix(s, "p"):
+		scale.Kind = ScalePercentile
+		if scale.Percentile, err = strconv.ParseFloat(s[1:], 64); err != nil || scale.Percentile < 0 || scale.Percentile >= 50 {
+			err = fmt.Errorf("wrong percentile in %q, should be in [0 50[", s)
+		}
+	case strings.Contains(s, ":"):
+		scale.Kind = ScaleFixed
+		parts := strings.SplitN(s, ":", 2)
+		if scale.Min, err = strconv.ParseFloat(parts[0], 64); err != nil {
+			return
+		}
+		if scale.Max, err = strconv.ParseFloat(parts[1], 64); err != nil {
+			return
+		}
+		if scale.Max <= scale.Min {
+			err = fmt.Errorf("max must be greater than min in %q", s)
+		}
+	default:
+		err = fmt.Errorf("unknown scale %q", s)
+	}
+	return
+}
+
+// percentile of sorted values, p in [0 100]
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := p / 100 * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	w := pos - float64(i)
+	return (1-w)*sorted[i] + w*sorted[i+1]
+}
+
+// Range of values according to scale
+func (s Scale) Range(values []float64) (min, max float64) {
+	if s.Kind == ScaleFixed {
+		return s.Min, s.Max
+	}
+	sorted := make([]float64, 0, len(values))
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			sorted = append(sorted, v)
+		}
+	}
+	if len(sorted) == 0 {
+		return
+	}
+	sort.Float64s(sorted)
+	min, max = sorted[0], sorted[len(sorted)-1]
+	switch s.Kind {
+	case ScaleSymmetric:
+		m := math.Max(math.Abs(min), math.Abs(max))
+		min, max = -m, m
+	case ScalePercentile:
+		min, max = percentile(sorted, s.Percentile), percentile(sorted, 100-s.Percentile)
+	}
+	return
+}
+
+// Ratio position of v in [min max], clamped to [0 1], NaN for NaN value
+func (s Scale) Ratio(v, min, max float64) float64 {
+	if math.IsNaN(v) {
+		return v
+	}
+	if max <= min {
+		return 0.5
+	}
+	return math.Max(0, math.Min(1, (v-min)/(max-min)))
+}
+
+// Mode value shown by DrawLap, with its color map and legend
+type Mode struct {
+	Name  string
+	Label string
+	// Unit and Factor used for legend, shown value is value*Factor
+	Unit   string
+	Factor float64
+	Value  func(gps []Timely, index int) float64
+	Scale  Scale
+	Colors ColorMap
+}
+
+// Display value as shown in legend
+func (m Mode) Display(v float64) float64 {
+	if m.Factor == 0 {
+		return v
+	}
+	return v * m.Factor
+}
+
+var (
+	modes      = make(map[string]Mode)
+	modesMutex sync.RWMutex
+)
+
+// RegisterMode add or replace a DrawLap mode
+func RegisterMode(m Mode) {
+	modesMutex.Lock()
+	defer modesMutex.Unlock()
+	modes[m.Name] = m
+}
+
+// GetMode registered mode by name, registered channels are modes too
+func GetMode(name string) (m Mode, ok bool) {
+	modesMutex.RLock()
+	m, ok = modes[name]
+	modesMutex.RUnlock()
+	if !ok {
+		var ch Channel
+		if ch, ok = GetChannel(name); ok {
+			m = ch.Mode()
+		}
+	}
+	return
+}
+
+// Modes names of all registered modes and channels, sorted
+func Modes() (names []string) {
+	modesMutex.RLock()
+	for name := range modes {
+		names = append(names, name)
+	}
+	modesMutex.RUnlock()
+	for _, name := range ChannelNames() {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return
+}
+
+func init() {
+	RegisterMode(Mode{
+		Name: "speed", Label: "Speed", Unit: "km/h", Factor: 3.6,
+		Value: GetSpeed, Scale: Scale{Kind: ScaleMinMax}, Colors: BlueGreenRed,
+	})
+	// drawn as accuracy circles
+	RegisterMode(Mode{
+		Name: "res", Label: "GPS accuracy", Unit: "cm",
+		Value: GetAccuracy, Scale: Scale{Kind: ScaleMinMax}, Colors: BlueGreenRed,
+	})
+	RegisterMode(Mode{
+		Name: "acc", Label: "Acceleration", Unit: "m/s²",
+		Value: GetAcc, Scale: Scale{Kind: ScaleSymmetric}, Colors: RedWhiteGreen,
+	})
+	RegisterMode(Mode{
+		Name: "lateral", Label: "Lateral G", Unit: "g", Factor: 1 / STANDARD_GRAVITY,
+		Value: GetLateralAcc, Scale: Scale{Kind: ScalePercentile, Percentile: 1}, Colors: Diverging,
+	})
+	RegisterMode(GearMode(GEAR_SHIFTS...))
+}
+
+// GEAR_SHIFTS usual shift speeds in km/h of a 6 speed KZ shifter kart
+var GEAR_SHIFTS = []float64{62, 82, 98, 111, 123}
+
+// GearMode gear estimated from speed only as there is no engine speed in
+// GoPro data, shifts are speeds in km/h where next gear is engaged.
+// A direct drive kart is always in gear 1.
+func GearMode(shifts ...float64) Mode {
+	shifts = slices.Clone(shifts)
+	slices.Sort(shifts)
+	return Mode{
+		Name: "gear", Label: "Gear estimate",
+		Value: func(gps []Timely, index int) float64 {
+			kmh := GetSpeed(gps, index) * 3.6
+			gear := 1
+			for _, s := range shifts {
+				if kmh >= s {
+					gear++
+				}
+			}
+			return float64(gear)
+		},
+		Scale:  Scale{Kind: ScaleFixed, Min: 1, Max: float64(max(2, len(shifts)+1))},
+		Colors: Viridis,
+	}
+}
+
+// DeltaMode time difference in seconds between lap and reference lap at same distance,
+// only valid to draw lap, negative when lap is ahead
+func (l LapCounter) DeltaMode(gps []Timely, reference, lap int) (m Mode, err error) {
+	rfrom, rto, err := l.LapRange(gps, reference)
+	if err != nil {
+		return
+	}
+	from, to, err := l.LapRange(gps, lap)
+	if err != nil {
+		return
+	}
+	ref := newLapTrace(gps, l.laps[reference][0], rfrom, rto)
+	current := newLapTrace(gps, l.laps[lap][0], from, to)
+	deltas := make([]float64, len(current.distance))
+	for i, d := range current.distance {
+		deltas[i] = math.NaN()
+		if elapsed, ok := ref.ElapsedAt(d); ok {
+			deltas[i] = (current.elapsed[i] - elapsed).Seconds()
+		}
+	}
+	m = Mode{
+		Name:  "delta",
+		Label: fmt.Sprintf("Delta to lap %d", reference),
+		Unit:  "s",
+		Value: func(gps []Timely, index int) float64 {
+			if index < from || index > to {
+				return math.NaN()
+			}
+			return deltas[index-from]
+		},
+		Scale:  Scale{Kind: ScaleSymmetric},
+		Colors: NewColorMap(green, white, red),
+	}
+	return
+}
+
+// DrawLegend color bar with title, unit and values in bottom right corner of img
+func (m Mode) DrawLegend(img *image.RGBA, lo, hi float64) {
+	r := img.Bounds()
+	size := max(14, r.Dy()/70)
+	w, h := 12*size, size
+	margin := size
+	x := r.Max.X - margin - w
+	y := r.Max.Y - margin - 2*size - h
+	// background
+	BlendRectangle(img, x-size/2, y-2*size, r.Max.X-margin+size/2, r.Max.Y-margin, WithAlpha(color.RGBA{0, 0, 0, 255}, 0.7))
+	title := m.Label
+	if m.Unit != "" {
+		title = fmt.Sprintf("%s (%s)", m.Label, m.Unit)
+	}
+	DrawText(img, x, y-2*size+size/4, title, size, white)
+	for i := 0; i < w; i++ {
+		c := m.Colors.At(float64(i) / float64(w-1))
+		DrawRectangle(img, x+i, y, x+i+1, y+h, c)
+	}
+	format := func(v float64) string {
+		v = m.Display(v)
+		if math.Abs(v) < 10 {
+			return strconv.FormatFloat(v, 'f', 1, 64)
+		}
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	for i, ratio := range []float64{0, 0.5, 1} {
+		tx := x + int(ratio*float64(w-1))
+		DrawRectangle(img, tx, y+h, tx+1, y+h+size/3, white)
+		label := format(lo + ratio*(hi-lo))
+		lw, _ := TextSize(label, size)
+		lx := tx - lw/2
+		switch i {
+		case 0:
+			lx = tx
+		case 2:
+			lx = tx - lw
+		}
+		DrawText(img, lx, y+h+size/3, label, size, white)
+	}
+}
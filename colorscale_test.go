@@ -0,0 +1,76 @@
+package gokart
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestScaleRange(t *testing.T) {
+	values := []float64{-2, 1, 3, 4, 100}
+	for _, test := range []struct {
+		scale    string
+		min, max float64
+	}{
+		{"minmax", -2, 100},
+		{"symmetric", -100, 100},
+		{"p25", 1, 4},
+		{"-10:10", -10, 10},
+	} {
+		scale, err := ParseScale(test.scale)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		min, max := scale.Range(values)
+		if min != test.min || max != test.max {
+			t.Errorf("%s range is [%f %f] should be [%f %f]", test.scale, min, max, test.min, test.max)
+		}
+	}
+}
+
+func TestColorMap(t *testing.T) {
+	m, err := ParseColorMap("#000000,#ff0000,#ffffff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := m.At(0.25); c != (color.RGBA{128, 0, 0, 255}) {
+		t.Errorf("color at 0.25 is %v", c)
+	}
+	if c := m.At(2); c != white {
+		t.Errorf("color above range is %v should be white", c)
+	}
+	if c := m.At(Scale{}.Ratio(math.NaN(), 0, 1)); c != grey {
+		t.Errorf("unknown value color is %v should be grey", c)
+	}
+}
+
+func TestGearMode(t *testing.T) {
+	m := GearMode(100, 50)
+	gps := make([]Timely, 3)
+	for i, kmh := range []float64{30, 60, 120} {
+		g := NewGPS5(47, 0.2)
+		g.Speed3D = kmh / 3.6
+		gps[i].Value = g
+	}
+	for i, gear := range []float64{1, 2, 3} {
+		if v := m.Value(gps, i); v != gear {
+			t.Errorf("gear of sample %d is %f should be %f", i, v, gear)
+		}
+	}
+	if lo, hi := m.Scale.Range(nil); lo != 1 || hi != 3 {
+		t.Errorf("gear range is [%f %f]", lo, hi)
+	}
+}
+
+func TestResMode(t *testing.T) {
+	m, ok := GetMode("res")
+	if !ok {
+		t.Fatal("no res mode")
+	}
+	g := NewGPS5(47, 0.2)
+	g.Accuracy = 250
+	if v := m.Display(m.Value([]Timely{{Value: g}}, 0)); m.Unit != "cm" || v != 250 {
+		t.Errorf("res legend is %f %s should be 250 cm", v, m.Unit)
+	}
+}
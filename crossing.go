@@ -0,0 +1,78 @@
+package gokart
+
+import (
+	"math"
+	"time"
+)
+
+// CROSSING_MARGIN meters a crossing may be beyond line ends, or away from
+// samples further than distance travelled between them
+const CROSSING_MARGIN = 8.0
+
+// Crossing when and where a line is crossed between two GPS samples
+type Crossing struct {
+	Time time.Time
+	// Fraction of distance between samples where line is crossed
+	Fraction float64
+	// Error estimate of Time, difference between constant speed and constant
+	// acceleration models plus GPS precision at crossing speed, at least time
+	// between samples when GPS was lost
+	Error time.Duration
+}
+
+// CrossLine exact intersection of segment g1 g2 with line in its tangent plane, time is interpolated
+// assuming constant acceleration between samples, false when line is not crossed
+func CrossLine(line Line, g1, g2 Timely) (c Crossing, ok bool) {
+	p1, p2 := g1.Value.(GPS5), g2.Value.(GPS5)
+	proj := line.Projection()
+	a, b := proj.Points(line)
+	x1, x2 := proj.Point(p1), proj.Point(p2)
+	s, r, ok := SegmentIntersection(x1, x2, a, b)
+	if !ok {
+		// parallel
+		return
+	}
+	ok = false
+	if s < 0 || s >= 1 {
+		// a sample on the line is only counted once, with following segment
+		return
+	}
+	length := b.Sub(a).Norm()
+	chord := x2.Sub(x1).Norm()
+	margin := CROSSING_MARGIN / length
+	if r < -margin || r > 1+margin {
+		// beyond line ends
+		return
+	}
+	d := g2.Time.Sub(g1.Time)
+	// samples of slow loggers are far apart, further than travelled GPS was lost
+	limit := CROSSING_MARGIN + max(p1.Speed, p2.Speed)*d.Seconds()
+	lost := s*chord > limit || (1-s)*chord > limit
+	u := speedFraction(s, p1.Speed, p2.Speed)
+	c = Crossing{
+		Time:     g1.Time.Add(time.Duration(float64(d)*u + 0.5)),
+		Fraction: s,
+		Error:    time.Duration(math.Abs(u-s) * float64(d)),
+	}
+	// position precision of the worst sample, about DOP meters
+	if speed := p1.Speed + (p2.Speed-p1.Speed)*u; speed > 0 {
+		dop := max(p1.DOP(), p2.DOP())
+		c.Error += time.Duration(dop / speed * float64(time.Second))
+	}
+	if lost {
+		// crossed somewhere between samples
+		c.Error = max(c.Error, d)
+	}
+	return c, true
+}
+
+// speedFraction part of time between samples needed to travel fraction s of
+// distance, speed changing linearly from v1 to v2
+func speedFraction(s, v1, v2 float64) float64 {
+	if v1 < 0 || v2 < 0 || v1+v2 == 0 || math.Abs(v2-v1) < 1e-9 {
+		return s
+	}
+	// v1*u + (v2-v1)*u²/2 = s*(v1+v2)/2
+	delta := v1*v1 + s*(v2*v2-v1*v1)
+	return (math.Sqrt(delta) - v1) / (v2 - v1)
+}
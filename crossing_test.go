@@ -0,0 +1,65 @@
+package gokart
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCrossLine(t *testing.T) {
+	// start line 20m wide from west to east, kart going north
+	dlat := 1 / 111320.0
+	dlon := dlat / math.Cos(47*math.Pi/180)
+	line := NewLine(47, 0.2-10*dlon, 47, 0.2+10*dlon)
+	t0 := time.Date(2024, 9, 14, 11, 12, 0, 0, time.UTC)
+	sample := func(north, east, speed float64, d time.Duration) Timely {
+		g := NewGPS5(47+north*dlat, 0.2+east*dlon)
+		g.Speed = speed
+		return Timely{Time: t0.Add(d), Value: g}
+	}
+
+	// constant speed, crossing at 40% of distance
+	c, ok := CrossLine(line, sample(-2, 0, 10, 0), sample(3, 0, 10, 500*time.Millisecond))
+	if !ok || math.Abs(c.Fraction-0.4) > 1e-3 || c.Time.Sub(t0).Round(time.Millisecond) != 200*time.Millisecond || c.Error != 0 {
+		t.Errorf("wrong constant speed crossing %+v %v", c, ok)
+	}
+
+	// accelerating from 5 to 15 m/s, half distance is reached after 0.618s
+	c, ok = CrossLine(line, sample(-5, 3, 5, 0), sample(5, 3, 15, time.Second))
+	if !ok || c.Time.Sub(t0).Round(time.Millisecond) != 618*time.Millisecond {
+		t.Errorf("wrong accelerating crossing %+v %v", c, ok)
+	}
+	if c.Error.Round(time.Millisecond) != 118*time.Millisecond {
+		t.Errorf("wrong error estimate %s", c.Error)
+	}
+
+	for name, g := range map[string][2]Timely{
+		"same side":   {sample(-5, 0, 10, 0), sample(-1, 0, 10, time.Second)},
+		"beyond ends": {sample(-2, 30, 10, 0), sample(2, 30, 10, time.Second)},
+		"parallel":    {sample(0, -5, 10, 0), sample(0, 5, 10, time.Second)},
+	} {
+		if c, ok := CrossLine(line, g[0], g[1]); ok {
+			t.Errorf("%s crossing found %+v", name, c)
+		}
+		if !Crossed(line, g[0], g[1]).IsZero() {
+			t.Errorf("%s crossed", name)
+		}
+	}
+
+	// 1Hz logger at 30 m/s
+	if c, ok = CrossLine(line, sample(-15, 0, 30, 0), sample(15, 0, 30, time.Second)); !ok || (c.Time.Sub(t0)-500*time.Millisecond).Abs() > time.Millisecond || c.Error != 0 {
+		t.Errorf("wrong slow logger crossing %+v %v", c, ok)
+	}
+	// GPS lost, samples are further than travelled at their speed
+	if c, ok = CrossLine(line, sample(-20, 0, 10, 0), sample(20, 0, 10, time.Second)); !ok || c.Error != time.Second {
+		t.Errorf("wrong crossing without GPS %+v %v", c, ok)
+	}
+
+	// sample exactly on line is counted once
+	on := sample(0, 0, 10, 100*time.Millisecond)
+	_, ok1 := CrossLine(line, sample(-1, 0, 10, 0), on)
+	_, ok2 := CrossLine(line, on, sample(1, 0, 10, 200*time.Millisecond))
+	if ok1 == ok2 {
+		t.Errorf("sample on line counted %v %v", ok1, ok2)
+	}
+}
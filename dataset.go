@@ -0,0 +1,267 @@
+package gokart
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"io"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// DATASET_FORMATS manifest formats of WriteManifest
+var DATASET_FORMATS = []string{"csv", "jsonl"}
+
+// DatasetConfig how frames of a session are sampled and split
+type DatasetConfig struct {
+	// Rate frames per second kept, every frame when 0
+	Rate float64
+	// Val and Test fraction of laps in validation and test sets, others are for training
+	Val  float64
+	Test float64
+}
+
+// DefaultDatasetConfig 2 frames per second, 70% of laps for training, 15% for validation and 15% for test
+var DefaultDatasetConfig = DatasetConfig{Rate: 2, Val: 0.15, Test: 0.15}
+
+// Validate rate and split fractions
+func (c DatasetConfig) Validate() error {
+	if c.Rate < 0 {
+		return errors.New("dataset rate must be positive")
+	}
+	if c.Val < 0 || c.Test < 0 || c.Val+c.Test > 1 {
+		return errors.New("validation and test fractions must be positive with a sum up to 1")
+	}
+	return nil
+}
+
+// Split "train", "val" or "test" set of a lap, all frames of a lap are in the
+// same set so that close frames are not in training and validation sets.
+// Same session name and lap are always in same set.
+func (c DatasetConfig) Split(session string, lap int) string {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s/%d", session, lap)
+	r := float64(h.Sum32()) / (1 << 32)
+	switch {
+	case r < c.Test:
+		return "test"
+	case r < c.Test+c.Val:
+		return "val"
+	}
+	return "train"
+}
+
+// DatasetLabel telemetry of one video frame
+type DatasetLabel struct {
+	// Frame number in video, first is 1
+	Frame int `json:"frame"`
+	// File of image, relative to manifest
+	File  string    `json:"file"`
+	Time  time.Time `json:"time"`
+	Track string    `json:"track"`
+	// Lap -1 before first start line
+	Lap int `json:"lap"`
+	// LapDistance metres since start line
+	LapDistance float64 `json:"lap_distance"`
+	// Speed in m/s
+	Speed float64 `json:"speed"`
+	// Acc longitudinal acceleration in m/s²
+	Acc float64 `json:"acc"`
+	// LateralG positive when turning right
+	LateralG float64 `json:"lateral_g"`
+	// Sector first is 1, 0 before first start line
+	Sector    int     `json:"sector"`
+	Corner    string  `json:"corner"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Split     string  `json:"split"`
+	// Driver, Kart and Camera model from session metadata
+	Driver string `json:"driver,omitempty"`
+	Kart   string `json:"kart,omitempty"`
+	Camera string `json:"camera,omitempty"`
+}
+
+// valueAt channel value at t, linear between samples around
+func valueAt(values []float64, gps []Timely, t time.Time) float64 {
+	i := FindIndex(t, gps)
+	if i < 0 {
+		return 0
+	}
+	span := gps[i+1].Time.Sub(gps[i].Time)
+	if span <= 0 {
+		return values[i]
+	}
+	f := float64(t.Sub(gps[i].Time)) / float64(span)
+	return values[i] + f*(values[i+1]-values[i])
+}
+
+// DatasetFrames frame numbers sampled at cfg.Rate, first is 1
+func DatasetFrames(info VideoInfo, cfg DatasetConfig) (frames []int) {
+	total := info.Frames
+	if total == 0 {
+		total = info.FrameAt(info.Duration)
+	}
+	if cfg.Rate == 0 || cfg.Rate >= info.FrameRate() {
+		for n := 1; n <= total; n++ {
+			frames = append(frames, n)
+		}
+		return
+	}
+	for k := 0; ; k++ {
+		n := info.FrameAt(time.Duration(float64(k)/cfg.Rate*float64(time.Second))) + 1
+		if n > total {
+			return
+		}
+		frames = append(frames, n)
+	}
+}
+
+// DatasetLabels labels of sampled frames with GPS data, video is assumed to
+// start at first GPS sample like other frame tools
+func (s *Session) DatasetLabels(info VideoInfo, cfg DatasetConfig) (labels []DatasetLabel, err error) {
+	if err = cfg.Validate(); err != nil {
+		return
+	}
+	if len(s.GPS) < 2 {
+		return nil, fmt.Errorf("no GPS point in %s", s.Filename)
+	}
+	c := s.Channels()
+	values := make(map[string][]float64)
+	for _, name := range []string{"distance", "speed", "acc", "latg"} {
+		if values[name], err = c.Get(name); err != nil {
+			return
+		}
+	}
+	laps := s.Laps.lapOf(s.GPS)
+	track := ""
+	var proj Projection
+	if s.Track != nil {
+		track = s.Track.Name
+		proj = s.Track.Start.Projection()
+	}
+	name := filepath.Base(s.Filename)
+	start := s.GPS[0].Time
+	for _, n := range DatasetFrames(info, cfg) {
+		t := start.Add(info.FrameTime(n - 1))
+		i := FindIndex(t, s.GPS)
+		if i < 0 {
+			// no GPS for this frame
+			continue
+		}
+		g, ierr := Interpolate(t, s.GPS[i], s.GPS[i+1])
+		if ierr != nil {
+			continue
+		}
+		pos := g.Value.(GPS5)
+		l := DatasetLabel{
+			Frame:     n,
+			Time:      t,
+			Track:     track,
+			Lap:       laps[i],
+			Speed:     valueAt(values["speed"], s.GPS, t),
+			Acc:       valueAt(values["acc"], s.GPS, t),
+			LateralG:  valueAt(values["latg"], s.GPS, t),
+			Latitude:  pos.Latitude,
+			Longitude: pos.Longitude,
+			Split:     cfg.Split(name, laps[i]),
+			Driver:    s.Meta.Driver,
+			Kart:      s.Meta.Kart,
+			Camera:    s.Meta.Camera.Model,
+		}
+		if l.Lap >= 0 {
+			l.LapDistance = valueAt(values["distance"], s.GPS, t) - valueAt(values["distance"], s.GPS, s.Laps.LapStart(l.Lap))
+			l.Sector = s.Laps.sectorAt(l.Lap, t) + 1
+		}
+		if s.Track != nil {
+			l.Corner = s.Track.closestCorner(proj, pos)
+		}
+		l.File = filepath.ToSlash(filepath.Join(l.Split, fmt.Sprintf("frame_%d.png", n)))
+		labels = append(labels, l)
+	}
+	return
+}
+
+// WriteManifest labels as kind "csv" with a header line or "jsonl" with one JSON object per line
+func WriteManifest(w io.Writer, kind string, labels []DatasetLabel) (err error) {
+	switch kind {
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, l := range labels {
+			if err = enc.Encode(l); err != nil {
+				return
+			}
+		}
+		return
+	case "csv":
+	default:
+		return fmt.Errorf("unknown manifest format %s", kind)
+	}
+	cw := csv.NewWriter(w)
+	if err = cw.Write([]string{"frame", "file", "time", "track", "lap", "lap_distance", "speed", "acc",
+		"lateral_g", "sector", "corner", "latitude", "longitude", "split", "driver", "kart", "camera"}); err != nil {
+		return
+	}
+	format := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	for _, l := range labels {
+		if err = cw.Write([]string{
+			strconv.Itoa(l.Frame),
+			l.File,
+			l.Time.Format(time.RFC3339Nano),
+			l.Track,
+			strconv.Itoa(l.Lap),
+			format(l.LapDistance),
+			format(l.Speed),
+			format(l.Acc),
+			format(l.LateralG),
+			strconv.Itoa(l.Sector),
+			l.Corner,
+			format(l.Latitude),
+			format(l.Longitude),
+			l.Split,
+			l.Driver,
+			l.Kart,
+			l.Camera,
+		}); err != nil {
+			return
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// PrepareFrame crop img to crop (whole image when empty) then resize to
+// width x height, a 0 dimension keeps aspect ratio, no resize when both are 0
+func PrepareFrame(img *image.RGBA, crop image.Rectangle, width, height int) (out *image.RGBA, err error) {
+	if crop.Empty() {
+		crop = img.Bounds()
+	}
+	if !crop.In(img.Bounds()) {
+		return nil, fmt.Errorf("crop %s out of image %s", crop, img.Bounds())
+	}
+	switch {
+	case width == 0 && height == 0:
+		width, height = crop.Dx(), crop.Dy()
+	case width == 0:
+		width = crop.Dx() * height / crop.Dy()
+	case height == 0:
+		height = crop.Dy() * width / crop.Dx()
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid frame size %dx%d", width, height)
+	}
+	out = image.NewRGBA(image.Rect(0, 0, width, height))
+	if width == crop.Dx() && height == crop.Dy() {
+		xdraw.Copy(out, image.Point{}, img, crop, xdraw.Src, nil)
+		return
+	}
+	xdraw.CatmullRom.Scale(out, out.Bounds(), img, crop, xdraw.Src, nil)
+	return
+}
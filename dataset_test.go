@@ -0,0 +1,126 @@
+package gokart
+
+import (
+	"bytes"
+	"encoding/csv"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestDatasetFrames(t *testing.T) {
+	info := VideoInfo{RateNum: 30, RateDen: 1, Frames: 300}
+	frames := DatasetFrames(info, DatasetConfig{Rate: 2})
+	if len(frames) != 20 || frames[0] != 1 || frames[1] != 16 || frames[19] != 286 {
+		t.Errorf("frames at 2 fps are %v", frames)
+	}
+	if frames := DatasetFrames(info, DatasetConfig{}); len(frames) != 300 {
+		t.Errorf("found %d frames should be every 300", len(frames))
+	}
+}
+
+func TestDatasetSplit(t *testing.T) {
+	cfg := DefaultDatasetConfig
+	count := make(map[string]int)
+	for lap := range 1000 {
+		split := cfg.Split("session.mp4", lap)
+		if split != cfg.Split("session.mp4", lap) {
+			t.Fatal("split should be deterministic")
+		}
+		count[split]++
+	}
+	if count["train"] < 600 || count["val"] < 100 || count["test"] < 100 {
+		t.Errorf("unbalanced split %v", count)
+	}
+	if err := (DatasetConfig{Val: 0.6, Test: 0.6}).Validate(); err == nil {
+		t.Error("fractions over 1 should fail")
+	}
+}
+
+func TestDatasetLabels(t *testing.T) {
+	s := circleSession([]float64{10, 12, 11})
+	s.Meta = Metadata{Driver: "Alice", Kart: "12", Camera: Camera{Model: "HERO9 Black"}}
+	duration := s.GPS[len(s.GPS)-1].Time.Sub(s.GPS[0].Time)
+	info := VideoInfo{RateNum: 25, RateDen: 1, Duration: duration}
+	labels, err := s.DatasetLabels(info, DatasetConfig{Rate: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) < int(duration.Seconds())-1 {
+		t.Fatalf("found %d labels for %s", len(labels), duration)
+	}
+	splits := make(map[int]string)
+	previous := DatasetLabel{Lap: -1}
+	for _, l := range labels {
+		if l.Track != "Circle" || !strings.HasPrefix(l.File, l.Split+"/") || l.Driver != "Alice" || l.Kart != "12" || l.Camera != "HERO9 Black" {
+			t.Fatalf("wrong label %+v", l)
+		}
+		if l.Lap >= 0 {
+			if l.Sector < 1 || l.Sector > 3 || l.LapDistance < 0 || l.LapDistance > 2*3.15*100 {
+				t.Errorf("wrong lap position %+v", l)
+			}
+			if l.Lap == previous.Lap && l.LapDistance <= previous.LapDistance {
+				t.Errorf("lap distance should increase %f then %f", previous.LapDistance, l.LapDistance)
+			}
+		}
+		if split, ok := splits[l.Lap]; ok && split != l.Split {
+			t.Errorf("lap %d is in %s and %s", l.Lap, split, l.Split)
+		}
+		splits[l.Lap] = l.Split
+		previous = l
+	}
+	var b bytes.Buffer
+	if err = WriteManifest(&b, "csv", labels); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(labels)+1 || records[0][5] != "lap_distance" || records[0][14] != "driver" {
+		t.Errorf("wrong manifest header %v", records[0])
+	}
+	if r := records[1]; r[14] != "Alice" || r[15] != "12" || r[16] != "HERO9 Black" {
+		t.Errorf("wrong manifest metadata %v", r)
+	}
+	if err = WriteManifest(&b, "parquet", labels); err == nil {
+		t.Error("unknown format should fail")
+	}
+}
+
+func TestDatasetLabelsWithoutTrack(t *testing.T) {
+	s := circleSession([]float64{10})
+	s.Track, s.Laps = nil, LapCounter{}
+	duration := s.GPS[len(s.GPS)-1].Time.Sub(s.GPS[0].Time)
+	labels, err := s.DatasetLabels(VideoInfo{RateNum: 25, RateDen: 1, Duration: duration}, DatasetConfig{Rate: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) == 0 {
+		t.Fatal("no label")
+	}
+	for _, l := range labels {
+		if l.Lap != -1 || l.Track != "" || l.Corner != "" || l.Sector != 0 {
+			t.Fatalf("label without track should have no lap %+v", l)
+		}
+	}
+}
+
+func TestPrepareFrame(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	img.Set(150, 50, color.RGBA{255, 0, 0, 255})
+	out, err := PrepareFrame(img, image.Rect(100, 0, 200, 100), 50, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Bounds() != image.Rect(0, 0, 50, 50) {
+		t.Errorf("size is %s should be 50x50", out.Bounds())
+	}
+	if out, _ = PrepareFrame(img, image.Rect(100, 0, 200, 100), 0, 0); out.RGBAAt(50, 50).R != 255 {
+		t.Error("crop should keep pixels")
+	}
+	if _, err = PrepareFrame(img, image.Rect(150, 0, 250, 100), 0, 0); err == nil {
+		t.Error("crop out of image should fail")
+	}
+}
@@ -4,6 +4,15 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"math"
+	"slices"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
 )
 
 func DrawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
@@ -13,11 +22,14 @@ func DrawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
 	line(point, img, x0, y0, x1, y1, c)
 }
 
+// DrawCircleLine line of width 2r+1 with round ends, anti-aliased
 func DrawCircleLine(img *image.RGBA, x0, y0, x1, y1, r int, c color.RGBA) {
-	circle := func(img *image.RGBA, x, y int, c color.RGBA) {
-		DrawCircle(img, x, y, r, c)
-	}
-	line(circle, img, x0, y0, x1, y1, c)
+	DrawPolyline(img, []Vertex{VertexOf(x0, y0), VertexOf(x1, y1)}, Stroke{
+		Width: float64(2*r + 1),
+		Color: c,
+		Join:  JoinRound,
+		Cap:   CapRound,
+	})
 }
 
 func line(f func(*image.RGBA, int, int, color.RGBA), img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
@@ -64,6 +76,11 @@ func DrawRectangle(img *image.RGBA, x1, y1, x2, y2 int, color color.RGBA) {
 	draw.Draw(img, image.Rect(x1, y1, x2, y2), &image.Uniform{color}, image.ZP, draw.Src)
 }
 
+// BlendRectangle draw a semi transparent rectangle, c alpha is used
+func BlendRectangle(img *image.RGBA, x1, y1, x2, y2 int, c color.RGBA) {
+	draw.Draw(img, image.Rect(x1, y1, x2, y2), &image.Uniform{c}, image.Point{}, draw.Over)
+}
+
 func DrawCircle(img *image.RGBA, x, y, r int, color color.RGBA) {
 	for px := -r; px <= r; px++ {
 		for py := -r; py <= r; py++ {
@@ -85,9 +102,13 @@ func DrawEmptyCircle(img *image.RGBA, x, y, r int, color color.RGBA) {
 }
 
 func DrawEllipse(img *image.RGBA, x, y, rx, ry int, color color.RGBA) {
+	if rx <= 0 || ry <= 0 {
+		return
+	}
 	for px := -rx; px <= rx; px++ {
 		for py := -ry; py <= ry; py++ {
-			if (px*px)/(rx*rx)+(py*py)/(ry*ry) <= 1 {
+			// integer form of px²/rx² + py²/ry² <= 1
+			if px*px*ry*ry+py*py*rx*rx <= rx*rx*ry*ry {
 				img.Set(x+px, y+py, color)
 			}
 		}
@@ -99,3 +120,373 @@ func abs(i int) int {
 	}
 	return i
 }
+
+var (
+	textFont  *opentype.Font
+	textFaces = make(map[int]font.Face)
+	textMutex sync.Mutex
+)
+
+// textFace embedded Go Mono Bold face for given pixel height,
+// textMutex must be held as faces are not safe for concurrent use
+func textFace(size int) (face font.Face, err error) {
+	if face = textFaces[size]; face != nil {
+		return
+	}
+	if textFont == nil {
+		if textFont, err = opentype.Parse(gomonobold.TTF); err != nil {
+			return
+		}
+	}
+	if face, err = opentype.NewFace(textFont, &opentype.FaceOptions{
+		Size:    float64(size),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	}); err != nil {
+		return
+	}
+	textFaces[size] = face
+	return
+}
+
+// TextSize width and height in pixels of text written with given size
+func TextSize(text string, size int) (w, h int) {
+	textMutex.Lock()
+	defer textMutex.Unlock()
+	face, err := textFace(size)
+	if err != nil {
+		return
+	}
+	w = font.MeasureString(face, text).Ceil()
+	h = face.Metrics().Height.Ceil()
+	return
+}
+
+// DrawText write text with its top left corner at x, y, size is text height in pixels
+func DrawText(img *image.RGBA, x, y int, text string, size int, c color.RGBA) (err error) {
+	textMutex.Lock()
+	defer textMutex.Unlock()
+	face, err := textFace(size)
+	if err != nil {
+		return
+	}
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y+face.Metrics().Ascent.Ceil()),
+	}
+	d.DrawString(text)
+	return
+}
+
+// Vertex pixel position with sub-pixel precision
+type Vertex struct {
+	X float64
+	Y float64
+}
+
+// VertexOf center of pixel x, y
+func VertexOf(x, y int) Vertex {
+	return Vertex{float64(x) + 0.5, float64(y) + 0.5}
+}
+
+// LineJoin shape used where two segments of a polyline meet
+type LineJoin int
+
+const (
+	JoinRound LineJoin = iota
+	JoinMiter
+	JoinBevel
+)
+
+// LineCap shape used at both ends of a polyline
+type LineCap int
+
+const (
+	CapRound LineCap = iota
+	CapButt
+	CapSquare
+)
+
+// Stroke how polylines are drawn
+type Stroke struct {
+	// Width in pixels
+	Width float64
+	Color color.RGBA
+	Join  LineJoin
+	Cap   LineCap
+	// MiterLimit max miter length relative to width before using a bevel, 4 when zero
+	MiterLimit float64
+}
+
+// WithAlpha c with given opacity in [0 1], alpha premultiplied as expected by image.RGBA
+func WithAlpha(c color.RGBA, alpha float64) color.RGBA {
+	alpha = math.Max(0, math.Min(1, alpha))
+	return color.RGBA{
+		uint8(float64(c.R)*alpha + 0.5),
+		uint8(float64(c.G)*alpha + 0.5),
+		uint8(float64(c.B)*alpha + 0.5),
+		uint8(float64(c.A)*alpha + 0.5),
+	}
+}
+
+// shape set of polygons filled together, overlaps are drawn once
+type shape struct {
+	polygons               [][]Vertex
+	minX, minY, maxX, maxY float64
+}
+
+// add polygon, all polygons get the same orientation so overlaps do not cancel
+func (s *shape) add(polygon ...Vertex) {
+	if len(polygon) < 3 {
+		return
+	}
+	area := 0.0
+	for i, v := range polygon {
+		w := polygon[(i+1)%len(polygon)]
+		area += v.X*w.Y - w.X*v.Y
+	}
+	if area > 0 {
+		slices.Reverse(polygon)
+	}
+	if len(s.polygons) == 0 {
+		s.minX, s.minY = polygon[0].X, polygon[0].Y
+		s.maxX, s.maxY = polygon[0].X, polygon[0].Y
+	}
+	for _, v := range polygon {
+		s.minX, s.maxX = math.Min(s.minX, v.X), math.Max(s.maxX, v.X)
+		s.minY, s.maxY = math.Min(s.minY, v.Y), math.Max(s.maxY, v.Y)
+	}
+	s.polygons = append(s.polygons, polygon)
+}
+
+// ellipse approximated by a polygon with about one vertex per pixel
+func (s *shape) ellipse(c Vertex, rx, ry float64) {
+	if rx <= 0 || ry <= 0 {
+		return
+	}
+	n := int(2 * math.Pi * math.Max(rx, ry))
+	n = max(12, min(n, 720))
+	polygon := make([]Vertex, n)
+	for i := range polygon {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		polygon[i] = Vertex{c.X + rx*math.Cos(a), c.Y + ry*math.Sin(a)}
+	}
+	s.add(polygon...)
+}
+
+// fill rasterize only shape bounding box, clipped to img
+func (s *shape) fill(img *image.RGBA, c color.RGBA) {
+	if len(s.polygons) == 0 {
+		return
+	}
+	r := image.Rect(
+		int(math.Floor(s.minX)), int(math.Floor(s.minY)),
+		int(math.Ceil(s.maxX))+1, int(math.Ceil(s.maxY))+1,
+	).Intersect(img.Bounds())
+	if r.Empty() {
+		return
+	}
+	z := vector.NewRasterizer(r.Dx(), r.Dy())
+	ox, oy := float64(r.Min.X), float64(r.Min.Y)
+	for _, polygon := range s.polygons {
+		z.MoveTo(float32(polygon[0].X-ox), float32(polygon[0].Y-oy))
+		for _, v := range polygon[1:] {
+			z.LineTo(float32(v.X-ox), float32(v.Y-oy))
+		}
+		z.ClosePath()
+	}
+	z.Draw(img, r, image.NewUniform(c), image.Point{})
+}
+
+// stroke add polyline outline to shape
+func (s *shape) stroke(points []Vertex, st Stroke) {
+	half := st.Width / 2
+	if half <= 0 || len(points) == 0 {
+		return
+	}
+	// remove duplicated points, they have no direction
+	clean := make([]Vertex, 0, len(points))
+	for _, p := range points {
+		if len(clean) == 0 || p != clean[len(clean)-1] {
+			clean = append(clean, p)
+		}
+	}
+	if len(clean) == 1 {
+		if st.Cap == CapRound {
+			s.ellipse(clean[0], half, half)
+		}
+		return
+	}
+	normal := func(a, b Vertex) (dx, dy, nx, ny float64) {
+		l := math.Hypot(b.X-a.X, b.Y-a.Y)
+		dx, dy = (b.X-a.X)/l, (b.Y-a.Y)/l
+		nx, ny = -dy*half, dx*half
+		return
+	}
+	last := len(clean) - 1
+	for i := 0; i < last; i++ {
+		a, b := clean[i], clean[i+1]
+		dx, dy, nx, ny := normal(a, b)
+		if st.Cap == CapSquare {
+			// extend both ends of the polyline by half width
+			if i == 0 {
+				a = Vertex{a.X - dx*half, a.Y - dy*half}
+			}
+			if i+1 == last {
+				b = Vertex{b.X + dx*half, b.Y + dy*half}
+			}
+		}
+		s.add(
+			Vertex{a.X + nx, a.Y + ny}, Vertex{b.X + nx, b.Y + ny},
+			Vertex{b.X - nx, b.Y - ny}, Vertex{a.X - nx, a.Y - ny})
+	}
+	if st.Cap == CapRound {
+		s.ellipse(clean[0], half, half)
+		s.ellipse(clean[last], half, half)
+	}
+	limit := st.MiterLimit
+	if limit <= 0 {
+		limit = 4
+	}
+	for i := 1; i < last; i++ {
+		p := clean[i]
+		if st.Join == JoinRound {
+			s.ellipse(p, half, half)
+			continue
+		}
+		dx1, dy1, nx1, ny1 := normal(clean[i-1], p)
+		dx2, dy2, nx2, ny2 := normal(p, clean[i+1])
+		// outer side of the turn
+		if dx1*dy2-dy1*dx2 < 0 {
+			nx1, ny1, nx2, ny2 = -nx1, -ny1, -nx2, -ny2
+		}
+		o1 := Vertex{p.X - nx1, p.Y - ny1}
+		o2 := Vertex{p.X - nx2, p.Y - ny2}
+		s.add(p, o1, o2)
+		if st.Join != JoinMiter {
+			continue
+		}
+		// miter tip on bisector
+		bx, by := -(nx1 + nx2), -(ny1 + ny2)
+		bl := math.Hypot(bx, by)
+		if bl == 0 {
+			continue
+		}
+		cos := bl / (2 * half)
+		if 1/cos > limit {
+			continue
+		}
+		tip := half / cos
+		s.add(o1, Vertex{p.X + bx/bl*tip, p.Y + by/bl*tip}, o2)
+	}
+}
+
+// DrawPolyline anti-aliased polyline with given stroke
+func DrawPolyline(img *image.RGBA, points []Vertex, st Stroke) {
+	var s shape
+	s.stroke(points, st)
+	s.fill(img, st.Color)
+}
+
+// FillPolygon anti-aliased filled polygon
+func FillPolygon(img *image.RGBA, points []Vertex, c color.RGBA) {
+	var s shape
+	s.add(slices.Clone(points)...)
+	s.fill(img, c)
+}
+
+// FillEllipse anti-aliased filled ellipse
+func FillEllipse(img *image.RGBA, center Vertex, rx, ry float64, c color.RGBA) {
+	var s shape
+	s.ellipse(center, rx, ry)
+	s.fill(img, c)
+}
+
+// StrokeEllipse anti-aliased ellipse outline of given width
+func StrokeEllipse(img *image.RGBA, center Vertex, rx, ry, width float64, c color.RGBA) {
+	n := int(2 * math.Pi * math.Max(rx, ry))
+	n = max(12, min(n, 720))
+	points := make([]Vertex, n+2)
+	for i := range points {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		points[i] = Vertex{center.X + rx*math.Cos(a), center.Y + ry*math.Sin(a)}
+	}
+	// one more point to join start and end
+	DrawPolyline(img, points, Stroke{Width: width, Color: c, Join: JoinBevel, Cap: CapButt})
+}
+
+// DrawArrow line from -> to with a filled head of given length at to
+func DrawArrow(img *image.RGBA, from, to Vertex, head float64, st Stroke) {
+	l := math.Hypot(to.X-from.X, to.Y-from.Y)
+	if l == 0 {
+		return
+	}
+	dx, dy := (to.X-from.X)/l, (to.Y-from.Y)/l
+	head = math.Min(head, l)
+	// line stops inside head
+	base := Vertex{to.X - dx*head, to.Y - dy*head}
+	st.Cap = CapButt
+	DrawPolyline(img, []Vertex{from, Vertex{base.X + dx*head/2, base.Y + dy*head/2}}, st)
+	w := math.Max(head/2, st.Width)
+	FillPolygon(img, []Vertex{
+		to,
+		{base.X - dy*w, base.Y + dx*w},
+		{base.X + dy*w, base.Y - dx*w},
+	}, st.Color)
+}
+
+// Anchor which point of a label is given
+type Anchor int
+
+const (
+	TopLeft Anchor = iota
+	TopCenter
+	TopRight
+	CenterLeft
+	Center
+	CenterRight
+	BottomLeft
+	BottomCenter
+	BottomRight
+)
+
+// DrawLabel text on a rounded background box, x, y is the anchor point of the box,
+// a transparent background draws text only
+func DrawLabel(img *image.RGBA, x, y int, text string, size int, fg, bg color.RGBA, anchor Anchor) (r image.Rectangle, err error) {
+	w, h := TextSize(text, size)
+	pad := size / 4
+	w, h = w+2*pad, h+pad
+	switch anchor % 3 {
+	case 1:
+		x -= w / 2
+	case 2:
+		x -= w
+	}
+	switch anchor / 3 {
+	case 1:
+		y -= h / 2
+	case 2:
+		y -= h
+	}
+	r = image.Rect(x, y, x+w, y+h)
+	if bg.A != 0 {
+		radius := float64(pad)
+		var s shape
+		fx, fy, fw, fh := float64(x), float64(y), float64(w), float64(h)
+		s.add(
+			Vertex{fx + radius, fy}, Vertex{fx + fw - radius, fy},
+			Vertex{fx + fw, fy + radius}, Vertex{fx + fw, fy + fh - radius},
+			Vertex{fx + fw - radius, fy + fh}, Vertex{fx + radius, fy + fh},
+			Vertex{fx, fy + fh - radius}, Vertex{fx, fy + radius})
+		for _, c := range []Vertex{
+			{fx + radius, fy + radius}, {fx + fw - radius, fy + radius},
+			{fx + fw - radius, fy + fh - radius}, {fx + radius, fy + fh - radius}} {
+			s.ellipse(c, radius, radius)
+		}
+		s.fill(img, bg)
+	}
+	err = DrawText(img, x+pad, y+pad/2, text, size, fg)
+	return
+}
@@ -0,0 +1,106 @@
+package gokart
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDrawEllipse(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	DrawEllipse(img, 10, 10, 4, 2, white)
+	for _, c := range []struct {
+		x, y int
+		set  bool
+	}{
+		{10, 10, true},
+		{14, 10, true},
+		{10, 12, true},
+		{15, 10, false},
+		// outside, was drawn when px²/rx² and py²/ry² were integer divisions
+		{13, 12, false},
+		{7, 8, false},
+	} {
+		if set := img.RGBAAt(c.x, c.y) == white; set != c.set {
+			t.Errorf("pixel %d,%d set:%v should be %v", c.x, c.y, set, c.set)
+		}
+	}
+	// no division by zero
+	DrawEllipse(img, 10, 10, 0, 2, white)
+}
+
+func TestDrawPolyline(t *testing.T) {
+	// L shape turning right, 6 pixels wide, from y 7.5 to 13.5 along first segment
+	points := []Vertex{{10.5, 10.5}, {30.5, 10.5}, {30.5, 30.5}}
+	gray := func(img *image.RGBA, x, y int) uint8 {
+		return img.RGBAAt(x, y).R
+	}
+	for _, c := range []struct {
+		join LineJoin
+		// coverage of pixel 32,8 at outer corner
+		min, max uint8
+	}{
+		{JoinMiter, 255, 255},
+		{JoinRound, 100, 220},
+		{JoinBevel, 0, 0},
+	} {
+		img := image.NewRGBA(image.Rect(0, 0, 50, 50))
+		DrawPolyline(img, points, Stroke{Width: 6, Color: white, Join: c.join, Cap: CapButt})
+		for y, want := range map[int]uint8{6: 0, 7: 128, 8: 255, 12: 255, 13: 128, 14: 0} {
+			if v := gray(img, 20, y); abs(int(v)-int(want)) > 2 {
+				t.Errorf("join %d pixel 20,%d is %d should be %d", c.join, y, v, want)
+			}
+		}
+		if v := gray(img, 32, 8); v < c.min || v > c.max {
+			t.Errorf("join %d corner is %d should be in [%d %d]", c.join, v, c.min, c.max)
+		}
+		// butt cap stops at first point
+		if v := gray(img, 9, 10); v != 0 {
+			t.Errorf("join %d butt cap drawn before start %d", c.join, v)
+		}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	DrawPolyline(img, points, Stroke{Width: 6, Color: white, Cap: CapRound})
+	if v := gray(img, 9, 10); v != 255 {
+		t.Errorf("round cap not drawn %d", v)
+	}
+}
+
+func TestFillEllipse(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	FillEllipse(img, Vertex{20, 20}, 10, 5, white)
+	if img.RGBAAt(20, 20) != white || img.RGBAAt(28, 20) != white || img.RGBAAt(20, 26) != (color.RGBA{}) {
+		t.Error("wrong filled ellipse")
+	}
+	img = image.NewRGBA(image.Rect(0, 0, 40, 40))
+	StrokeEllipse(img, Vertex{20, 20}, 10, 10, 2, white)
+	if img.RGBAAt(20, 20) != (color.RGBA{}) || img.RGBAAt(29, 19) != white || img.RGBAAt(19, 10) != white {
+		t.Error("wrong ellipse outline")
+	}
+}
+
+func TestDrawArrow(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	DrawArrow(img, Vertex{5, 20}, Vertex{35, 20}, 10, Stroke{Width: 2, Color: white})
+	// head is wider than line
+	if img.RGBAAt(26, 22) != white || img.RGBAAt(15, 22) != (color.RGBA{}) || img.RGBAAt(15, 19) != white {
+		t.Error("wrong arrow")
+	}
+}
+
+func TestDrawLabel(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	r, err := DrawLabel(img, 100, 50, "1:02.345", 20, white, red, Center)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := r.Min.Add(r.Max).Div(2); c.X < 99 || c.X > 101 || c.Y < 49 || c.Y > 51 {
+		t.Errorf("label %v is not centered", r)
+	}
+	if r, _ = DrawLabel(img, 200, 100, "P1", 20, white, red, BottomRight); r.Max != (image.Point{200, 100}) {
+		t.Errorf("label %v is not at bottom right", r)
+	}
+	if img.RGBAAt(r.Min.X+r.Dx()/2, r.Max.Y-2) == (color.RGBA{}) {
+		t.Error("no label background")
+	}
+}
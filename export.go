@@ -0,0 +1,153 @@
+package gokart
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// lapOf lap number of each GPS sample, -1 before first start line
+func (l LapCounter) lapOf(gps []Timely) (laps []int) {
+	laps = make([]int, len(gps))
+	// lap 0 is recorded before first start line, without start time
+	lap := 0
+	for i, g := range gps {
+		for lap+1 < len(l.laps) && !l.laps[lap+1][0].IsZero() && !g.Time.Before(l.laps[lap+1][0]) {
+			lap++
+		}
+		laps[i] = lap
+		if lap >= len(l.laps) || l.laps[lap][0].IsZero() {
+			laps[i] = -1
+		}
+	}
+	return
+}
+
+// WriteCSV one line per GPS sample, lap is -1 before first start line,
+// driver and kart are repeated so that files of several sessions can be concatenated,
+// given channels are added as last columns in SI units
+func (s *Session) WriteCSV(w io.Writer, channels ...string) (err error) {
+	values := make([][]float64, len(channels))
+	for i, name := range channels {
+		if values[i], err = s.Channels().Get(name); err != nil {
+			return
+		}
+	}
+	cw := csv.NewWriter(w)
+	header := []string{"time", "lap", "latitude", "longitude", "altitude", "speed", "speed3d", "accuracy", "driver", "kart"}
+	if err = cw.Write(append(header, channels...)); err != nil {
+		return
+	}
+	laps := s.Laps.lapOf(s.GPS)
+	format := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	for i, g := range s.GPS {
+		v := g.Value.(GPS5)
+		record := []string{
+			g.Time.Format(time.RFC3339Nano),
+			strconv.Itoa(laps[i]),
+			format(v.Latitude),
+			format(v.Longitude),
+			format(v.Altitude),
+			format(v.Speed),
+			format(v.Speed3D),
+			strconv.Itoa(int(v.Accuracy)),
+			s.Meta.Driver,
+			s.Meta.Kart,
+		}
+		for _, c := range values {
+			record = append(record, format(c[i]))
+		}
+		if err = cw.Write(record); err != nil {
+			return
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type gpxPoint struct {
+	Latitude  float64 `xml:"lat,attr"`
+	Longitude float64 `xml:"lon,attr"`
+	Elevation float64 `xml:"ele"`
+	Time      string  `xml:"time"`
+	Speed     float64 `xml:"extensions>speed"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxMetadata struct {
+	Name        string `xml:"name,omitempty"`
+	Description string `xml:"desc,omitempty"`
+	Author      string `xml:"author>name,omitempty"`
+}
+
+type gpx struct {
+	XMLName  xml.Name     `xml:"gpx"`
+	Version  string       `xml:"version,attr"`
+	Creator  string       `xml:"creator,attr"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	Metadata *gpxMetadata `xml:"metadata"`
+	Tracks   []gpxTrack   `xml:"trk"`
+}
+
+// WriteGPX GPX 1.1 file with one segment per lap, speed in m/s as extension,
+// driver is the author and other metadata the description
+func (s *Session) WriteGPX(w io.Writer) (err error) {
+	name := s.Filename
+	if s.Track != nil {
+		name = s.Track.Name
+	}
+	doc := gpx{
+		Version: "1.1",
+		Creator: "gokart",
+		XMLNS:   "http://www.topografix.com/GPX/1/1",
+		Tracks:  []gpxTrack{{Name: name}},
+	}
+	if !s.Meta.IsZero() {
+		doc.Metadata = &gpxMetadata{
+			Name:        name,
+			Description: s.Meta.Description(),
+			Author:      s.Meta.Driver,
+		}
+	}
+	laps := s.Laps.lapOf(s.GPS)
+	current := 0
+	for i, g := range s.GPS {
+		if i == 0 || laps[i] != current {
+			current = laps[i]
+			doc.Tracks[0].Segments = append(doc.Tracks[0].Segments, gpxSegment{})
+		}
+		v := g.Value.(GPS5)
+		seg := &doc.Tracks[0].Segments[len(doc.Tracks[0].Segments)-1]
+		seg.Points = append(seg.Points, gpxPoint{
+			Latitude:  v.Latitude,
+			Longitude: v.Longitude,
+			Elevation: v.Altitude,
+			Time:      g.Time.UTC().Format(time.RFC3339Nano),
+			Speed:     v.Speed3D,
+		})
+	}
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err = enc.Encode(doc); err != nil {
+		err = fmt.Errorf("unable to encode gpx:%s", err)
+		return
+	}
+	_, err = io.WriteString(w, "\n")
+	return
+}
@@ -0,0 +1,37 @@
+package gokart
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestExportWithoutTrack(t *testing.T) {
+	_, gps, _ := circleLaps([]float64{10})
+	// unknown track, laps are not counted
+	s := &Session{Filename: "circle.mp4", GPS: gps}
+	var b bytes.Buffer
+	if err := s.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(s.GPS)+1 {
+		t.Fatalf("wrote %d lines for %d samples", len(records), len(s.GPS))
+	}
+	for _, r := range records[1:] {
+		if r[1] != "-1" {
+			t.Fatalf("lap should be -1 %v", r)
+		}
+	}
+	b.Reset()
+	if err = s.WriteGPX(&b); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(b.String(), "<trkseg>") != 1 {
+		t.Error("all samples should be in one segment")
+	}
+}
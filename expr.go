@@ -0,0 +1,290 @@
+package gokart
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression formula over channels, evaluated for every sample
+type Expression interface {
+	Eval(c *Channels) ([]float64, error)
+}
+
+// constant same value for every sample
+type constant float64
+
+func (k constant) Eval(c *Channels) (values []float64, err error) {
+	values = make([]float64, c.Len())
+	for i := range values {
+		values[i] = float64(k)
+	}
+	return
+}
+
+// channelRef values of a registered channel
+type channelRef string
+
+func (r channelRef) Eval(c *Channels) ([]float64, error) {
+	return c.Get(string(r))
+}
+
+// operation binary operator applied sample by sample
+type operation struct {
+	op          byte
+	left, right Expression
+}
+
+func (b operation) Eval(c *Channels) (values []float64, err error) {
+	left, err := b.left.Eval(c)
+	if err != nil {
+		return
+	}
+	right, err := b.right.Eval(c)
+	if err != nil {
+		return
+	}
+	values = make([]float64, len(left))
+	for i := range left {
+		switch b.op {
+		case '+':
+			values[i] = left[i] + right[i]
+		case '-':
+			values[i] = left[i] - right[i]
+		case '*':
+			values[i] = left[i] * right[i]
+		case '/':
+			values[i] = left[i] / right[i]
+		case '^':
+			values[i] = math.Pow(left[i], right[i])
+		}
+	}
+	return
+}
+
+// call of a function of EXPRESSION_FUNCTIONS
+type call struct {
+	name string
+	args []Expression
+}
+
+// expressionFunction number of arguments and evaluation from their values
+type expressionFunction struct {
+	args int
+	eval func(c *Channels, args [][]float64) ([]float64, error)
+}
+
+// sampleWise function applied on each sample
+func sampleWise(f func(v ...float64) float64) func(c *Channels, args [][]float64) ([]float64, error) {
+	return func(c *Channels, args [][]float64) (values []float64, err error) {
+		values = make([]float64, c.Len())
+		v := make([]float64, len(args))
+		for i := range values {
+			for j := range args {
+				v[j] = args[j][i]
+			}
+			values[i] = f(v...)
+		}
+		return
+	}
+}
+
+// EXPRESSION_FUNCTIONS available in expressions
+var EXPRESSION_FUNCTIONS = map[string]expressionFunction{
+	"abs":  {1, sampleWise(func(v ...float64) float64 { return math.Abs(v[0]) })},
+	"sqrt": {1, sampleWise(func(v ...float64) float64 { return math.Sqrt(v[0]) })},
+	"sin":  {1, sampleWise(func(v ...float64) float64 { return math.Sin(v[0]) })},
+	"cos":  {1, sampleWise(func(v ...float64) float64 { return math.Cos(v[0]) })},
+	"min":  {2, sampleWise(func(v ...float64) float64 { return math.Min(v[0], v[1]) })},
+	"max":  {2, sampleWise(func(v ...float64) float64 { return math.Max(v[0], v[1]) })},
+	// avg(x, n) rolling average of x over n samples before and after
+	"avg": {2, func(c *Channels, args [][]float64) ([]float64, error) {
+		if len(args[1]) == 0 {
+			return args[0], nil
+		}
+		return movingAverage(args[0], int(args[1][0])), nil
+	}},
+	// diff(x) derivative of x per second
+	"diff": {1, func(c *Channels, args [][]float64) ([]float64, error) {
+		t, err := c.Get("time")
+		if err != nil {
+			return nil, err
+		}
+		return derivative(args[0], t), nil
+	}},
+}
+
+func (f call) Eval(c *Channels) (values []float64, err error) {
+	args := make([][]float64, len(f.args))
+	for i, a := range f.args {
+		if args[i], err = a.Eval(c); err != nil {
+			return
+		}
+	}
+	return EXPRESSION_FUNCTIONS[f.name].eval(c, args)
+}
+
+// exprParser recursive descent parser of an expression
+type exprParser struct {
+	s   string
+	pos int
+}
+
+// ParseExpression parse a formula over channels with numbers, channel names,
+// + - * / ^, parentheses and functions of EXPRESSION_FUNCTIONS, like
+// "speed^2 * curvature / 9.81" or "diff(avg(acc, 4))"
+func ParseExpression(s string) (e Expression, err error) {
+	p := &exprParser{s: s}
+	if e, err = p.sum(); err != nil {
+		return
+	}
+	p.skip()
+	if p.pos < len(p.s) {
+		return nil, fmt.Errorf("unexpected %q at %d in %q", p.s[p.pos], p.pos, s)
+	}
+	return
+}
+
+// skip spaces
+func (p *exprParser) skip() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// next operator if it is one of ops
+func (p *exprParser) next(ops string) (op byte, ok bool) {
+	p.skip()
+	if p.pos < len(p.s) && strings.IndexByte(ops, p.s[p.pos]) >= 0 {
+		op = p.s[p.pos]
+		p.pos++
+		return op, true
+	}
+	return
+}
+
+// sum term (+|- term)*
+func (p *exprParser) sum() (e Expression, err error) {
+	if e, err = p.product(); err != nil {
+		return
+	}
+	for op, ok := p.next("+-"); ok; op, ok = p.next("+-") {
+		var right Expression
+		if right, err = p.product(); err != nil {
+			return
+		}
+		e = operation{op, e, right}
+	}
+	return
+}
+
+// product unary (*|/ unary)*
+func (p *exprParser) product() (e Expression, err error) {
+	if e, err = p.unary(); err != nil {
+		return
+	}
+	for op, ok := p.next("*/"); ok; op, ok = p.next("*/") {
+		var right Expression
+		if right, err = p.unary(); err != nil {
+			return
+		}
+		e = operation{op, e, right}
+	}
+	return
+}
+
+// unary -unary or power
+func (p *exprParser) unary() (e Expression, err error) {
+	if _, ok := p.next("-"); ok {
+		if e, err = p.unary(); err != nil {
+			return
+		}
+		return operation{'-', constant(0), e}, nil
+	}
+	return p.power()
+}
+
+// power primary (^ unary)?, right associative
+func (p *exprParser) power() (e Expression, err error) {
+	if e, err = p.primary(); err != nil {
+		return
+	}
+	if _, ok := p.next("^"); ok {
+		var right Expression
+		if right, err = p.unary(); err != nil {
+			return
+		}
+		e = operation{'^', e, right}
+	}
+	return
+}
+
+// primary number, channel, function call or parenthesized expression
+func (p *exprParser) primary() (e Expression, err error) {
+	p.skip()
+	if p.pos >= len(p.s) {
+		return nil, fmt.Errorf("unexpected end of %q", p.s)
+	}
+	start := p.pos
+	switch c := rune(p.s[p.pos]); {
+	case c == '(':
+		p.pos++
+		if e, err = p.sum(); err != nil {
+			return
+		}
+		if _, ok := p.next(")"); !ok {
+			return nil, fmt.Errorf("missing ) in %q", p.s)
+		}
+		return
+	case unicode.IsDigit(c) || c == '.':
+		for p.pos < len(p.s) && (unicode.IsDigit(rune(p.s[p.pos])) || p.s[p.pos] == '.') {
+			p.pos++
+		}
+		v, perr := strconv.ParseFloat(p.s[start:p.pos], 64)
+		if perr != nil {
+			return nil, fmt.Errorf("invalid number %q:%w", p.s[start:p.pos], perr)
+		}
+		return constant(v), nil
+	case unicode.IsLetter(c) || c == '_':
+		for p.pos < len(p.s) && (unicode.IsLetter(rune(p.s[p.pos])) || unicode.IsDigit(rune(p.s[p.pos])) || p.s[p.pos] == '_') {
+			p.pos++
+		}
+		name := p.s[start:p.pos]
+		if _, ok := p.next("("); !ok {
+			return channelRef(name), nil
+		}
+		return p.call(name)
+	}
+	return nil, fmt.Errorf("unexpected %q at %d in %q", p.s[p.pos], p.pos, p.s)
+}
+
+// call arguments of function name, opening parenthesis already read
+func (p *exprParser) call(name string) (e Expression, err error) {
+	f, ok := EXPRESSION_FUNCTIONS[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	var args []Expression
+	if _, ok := p.next(")"); !ok {
+		for {
+			var arg Expression
+			if arg, err = p.sum(); err != nil {
+				return
+			}
+			args = append(args, arg)
+			if _, ok := p.next(")"); ok {
+				break
+			}
+			if _, ok := p.next(","); !ok {
+				return nil, errors.New("missing , or ) after argument of " + name)
+			}
+		}
+	}
+	if len(args) != f.args {
+		return nil, fmt.Errorf("%s needs %d arguments, got %d", name, f.args, len(args))
+	}
+	return call{name, args}, nil
+}
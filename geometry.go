@@ -0,0 +1,192 @@
+package gokart
+
+import "math"
+
+// WGS84 ellipsoid used by GPS
+const (
+	WGS84_A = 6378137.0
+	WGS84_F = 1 / 298.257223563
+)
+
+// wgs84E2 square of first eccentricity
+const wgs84E2 = WGS84_F * (2 - WGS84_F)
+
+// Point metres east and north in a local tangent plane
+type Point struct {
+	E float64 `json:"e"`
+	N float64 `json:"n"`
+}
+
+// Add p + q
+func (p Point) Add(q Point) Point {
+	return Point{p.E + q.E, p.N + q.N}
+}
+
+// Sub p - q
+func (p Point) Sub(q Point) Point {
+	return Point{p.E - q.E, p.N - q.N}
+}
+
+// Scale p × k
+func (p Point) Scale(k float64) Point {
+	return Point{p.E * k, p.N * k}
+}
+
+// Dot product
+func (p Point) Dot(q Point) float64 {
+	return p.E*q.E + p.N*q.N
+}
+
+// Cross z of cross product, positive when q is on the left of p
+func (p Point) Cross(q Point) float64 {
+	return p.E*q.N - p.N*q.E
+}
+
+// Norm length in metres
+func (p Point) Norm() float64 {
+	return math.Hypot(p.E, p.N)
+}
+
+// Projection local East-North-Up frame tangent to WGS84 ellipsoid at Origin
+type Projection struct {
+	Origin GPS5
+	// earth centered earth fixed coordinates of origin
+	x0, y0, z0                     float64
+	sinLat, cosLat, sinLon, cosLon float64
+}
+
+// NewProjection frame around origin, usually center of a track
+func NewProjection(origin GPS5) (p Projection) {
+	p.Origin = origin
+	lat, lon := origin.Latitude*math.Pi/180, origin.Longitude*math.Pi/180
+	p.sinLat, p.cosLat = math.Sincos(lat)
+	p.sinLon, p.cosLon = math.Sincos(lon)
+	p.x0, p.y0, p.z0 = ecef(origin.Latitude, origin.Longitude, origin.Altitude)
+	return
+}
+
+// ecef earth centered earth fixed coordinates in metres
+func ecef(lat, lon, alt float64) (x, y, z float64) {
+	sinLat, cosLat := math.Sincos(lat * math.Pi / 180)
+	sinLon, cosLon := math.Sincos(lon * math.Pi / 180)
+	n := WGS84_A / math.Sqrt(1-wgs84E2*sinLat*sinLat)
+	x = (n + alt) * cosLat * cosLon
+	y = (n + alt) * cosLat * sinLon
+	z = (n*(1-wgs84E2) + alt) * sinLat
+	return
+}
+
+// ENU east, north and up metres of g from origin
+func (p Projection) ENU(g GPS5) (e, n, u float64) {
+	x, y, z := ecef(g.Latitude, g.Longitude, g.Altitude)
+	dx, dy, dz := x-p.x0, y-p.y0, z-p.z0
+	e = -p.sinLon*dx + p.cosLon*dy
+	n = -p.sinLat*p.cosLon*dx - p.sinLat*p.sinLon*dy + p.cosLat*dz
+	u = p.cosLat*p.cosLon*dx + p.cosLat*p.sinLon*dy + p.sinLat*dz
+	return
+}
+
+// Point position of g in tangent plane, altitude of g is ignored
+func (p Projection) Point(g GPS5) Point {
+	g.Altitude = p.Origin.Altitude
+	e, n, _ := p.ENU(g)
+	return Point{e, n}
+}
+
+// GPS5 latitude and longitude of pt, inverse of Point
+func (p Projection) GPS5(pt Point) (g GPS5) {
+	// back to earth centered earth fixed
+	x := p.x0 - p.sinLon*pt.E - p.sinLat*p.cosLon*pt.N
+	y := p.y0 + p.cosLon*pt.E - p.sinLat*p.sinLon*pt.N
+	z := p.z0 + p.cosLat*pt.N
+	lon := math.Atan2(y, x)
+	r := math.Hypot(x, y)
+	lat := math.Atan2(z, r*(1-wgs84E2))
+	for range 5 {
+		sinLat := math.Sin(lat)
+		n := WGS84_A / math.Sqrt(1-wgs84E2*sinLat*sinLat)
+		lat = math.Atan2(z+wgs84E2*n*sinLat, r)
+	}
+	return NewGPS5(lat*180/math.Pi, lon*180/math.Pi)
+}
+
+// Points both ends of l in tangent plane
+func (p Projection) Points(l Line) (a, b Point) {
+	return p.Point(l.P1), p.Point(l.P2)
+}
+
+// Heading radians from a to b, 0 is north, clockwise
+func Heading(a, b Point) float64 {
+	d := b.Sub(a)
+	return math.Atan2(d.E, d.N)
+}
+
+// SegmentIntersection where lines through a1 a2 and b1 b2 cross, s along a
+// and r along b as fractions of their length, false when parallel
+func SegmentIntersection(a1, a2, b1, b2 Point) (s, r float64, ok bool) {
+	da, db := a2.Sub(a1), b2.Sub(b1)
+	det := da.Cross(db)
+	if det == 0 {
+		return
+	}
+	w := b1.Sub(a1)
+	return w.Cross(db) / det, w.Cross(da) / det, true
+}
+
+// SegmentDistance distance from p to segment a b and fraction t of closest point
+func SegmentDistance(p, a, b Point) (d, t float64) {
+	ab := b.Sub(a)
+	if l2 := ab.Dot(ab); l2 > 0 {
+		t = min(1, max(0, p.Sub(a).Dot(ab)/l2))
+	}
+	return p.Sub(a.Add(ab.Scale(t))).Norm(), t
+}
+
+// Polyline open path of points
+type Polyline []Point
+
+// Length in metres
+func (pl Polyline) Length() (l float64) {
+	for i := 1; i < len(pl); i++ {
+		l += pl[i].Sub(pl[i-1]).Norm()
+	}
+	return
+}
+
+// Closest segment index i (from pl[i] to pl[i+1]) closest to p, with distance
+// to it and distance along polyline, -1 when there is no segment
+func (pl Polyline) Closest(p Point) (i int, d, along float64) {
+	i = -1
+	start := 0.0
+	for j := 1; j < len(pl); j++ {
+		dj, t := SegmentDistance(p, pl[j-1], pl[j])
+		l := pl[j].Sub(pl[j-1]).Norm()
+		if i < 0 || dj < d {
+			i, d, along = j-1, dj, start+t*l
+		}
+		start += l
+	}
+	return
+}
+
+// Polygon closed shape, last point is joined to first one
+type Polygon []Point
+
+// Area signed, positive when points are counter clockwise
+func (pg Polygon) Area() (a float64) {
+	for i := range pg {
+		a += pg[i].Cross(pg[(i+1)%len(pg)])
+	}
+	return a / 2
+}
+
+// Contains p is inside polygon, even-odd rule
+func (pg Polygon) Contains(p Point) (in bool) {
+	for i := range pg {
+		a, b := pg[i], pg[(i+1)%len(pg)]
+		if (a.N > p.N) != (b.N > p.N) && p.E < a.E+(p.N-a.N)*(b.E-a.E)/(b.N-a.N) {
+			in = !in
+		}
+	}
+	return
+}
@@ -0,0 +1,73 @@
+package gokart
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeometry(t *testing.T) {
+	origin := NewGPS5(47.3903, -1.1604)
+	p := NewProjection(origin)
+	if pt := p.Point(origin); pt.Norm() > 1e-6 {
+		t.Errorf("origin projected at %+v", pt)
+	}
+	// meridian arc of 0.001° at 47.39° is 111.18m on WGS84
+	if d := Distance(origin, NewGPS5(47.3913, -1.1604)); math.Abs(d-111.18) > 0.01 {
+		t.Errorf("north distance %f", d)
+	}
+	for _, pt := range []Point{{120, -45}, {-800, 300}, {2500, 2500}} {
+		back := p.Point(p.GPS5(pt))
+		if back.Sub(pt).Norm() > 1e-3 {
+			t.Errorf("%+v projected back at %+v", pt, back)
+		}
+	}
+	if h := Heading(Point{}, Point{E: 1}); math.Abs(h-math.Pi/2) > 1e-9 {
+		t.Errorf("east heading %f", h)
+	}
+
+	// crossing near longitude 0 and limits
+	limits := ExtractLimits([]Timely{
+		{Value: NewGPS5(47.001, -0.0005)},
+		{Value: NewGPS5(46.999, 0.0004)},
+	})
+	if math.Abs(limits.P1.Latitude-46.999) > 1e-7 || math.Abs(limits.P1.Longitude+0.0005) > 1e-7 ||
+		math.Abs(limits.P2.Latitude-47.001) > 1e-7 || math.Abs(limits.P2.Longitude-0.0004) > 1e-7 {
+		t.Errorf("wrong limits %+v", limits)
+	}
+
+	s, r, ok := SegmentIntersection(Point{0, -1}, Point{0, 3}, Point{-2, 0}, Point{2, 0})
+	if !ok || s != 0.25 || r != 0.5 {
+		t.Errorf("wrong intersection %f %f %v", s, r, ok)
+	}
+	if _, _, ok = SegmentIntersection(Point{0, 0}, Point{1, 1}, Point{1, 0}, Point{2, 1}); ok {
+		t.Errorf("parallel segments intersect")
+	}
+	if d, tt := SegmentDistance(Point{5, 2}, Point{0, 0}, Point{4, 0}); tt != 1 || math.Abs(d-math.Sqrt(5)) > 1e-9 {
+		t.Errorf("wrong segment distance %f at %f", d, tt)
+	}
+
+	pl := Polyline{{0, 0}, {10, 0}, {10, 10}}
+	if pl.Length() != 20 {
+		t.Errorf("polyline length %f", pl.Length())
+	}
+	if i, d, along := pl.Closest(Point{12, 4}); i != 1 || d != 2 || along != 14 {
+		t.Errorf("closest segment %d at %f along %f", i, d, along)
+	}
+
+	square := Polygon{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	if square.Area() != 100 {
+		t.Errorf("square area %f", square.Area())
+	}
+	if !square.Contains(Point{5, 5}) || square.Contains(Point{15, 5}) {
+		t.Errorf("wrong contains")
+	}
+
+	// line from west to east, north is on the left
+	line := NewLine(47, 0.2, 47, 0.2002)
+	if line.Side(NewGPS5(47.0001, 0.2001)) != 1 || line.Side(NewGPS5(46.9999, 0.2001)) != -1 {
+		t.Errorf("wrong sides")
+	}
+	if d := line.To(NewGPS5(47.0001, 0.2001)); math.Abs(d-11.12) > 0.01 {
+		t.Errorf("distance to line %f", d)
+	}
+}
@@ -5,7 +5,7 @@ go 1.23.2
 require (
 	github.com/cedricjoulain/gopro-utils v0.0.0-20241020122436-c58588334857
 	github.com/u2takey/ffmpeg-go v0.5.0
-	gocv.io/x/gocv v0.41.0
+	golang.org/x/image v0.25.0
 )
 
 require (
@@ -14,4 +14,5 @@ require (
 	github.com/paulmach/go.geo v0.0.0-20180829195134-22b514266d33 // indirect
 	github.com/paulmach/go.geojson v1.5.0 // indirect
 	github.com/u2takey/go-utils v0.3.1 // indirect
+	golang.org/x/text v0.23.0 // indirect
 )
@@ -10,6 +10,13 @@ import (
 type GPS5 struct {
 	telemetry.GPS5
 	Accuracy uint16 `json:"accuracy,omitempty"` // gps accuracy in cm
+	// Fix 0 no fix, 2 2D, 3 3D
+	Fix uint16 `json:"fix,omitempty"`
+}
+
+// DOP dilution of precision, under 5 is good
+func (g GPS5) DOP() float64 {
+	return float64(g.Accuracy) / 100
 }
 
 // NewGPS5 simple constructor from Latitude and Longitude
@@ -49,17 +56,24 @@ func NewLine(lat1, lon1, lat2, lon2 float64) (l Line) {
 	return
 }
 
+// Projection tangent plane around middle of line
+func (l Line) Projection() Projection {
+	return NewProjection(NewGPS5((l.P1.Latitude+l.P2.Latitude)/2, (l.P1.Longitude+l.P2.Longitude)/2))
+}
+
+// To signed distance in metres from g to line segment, sign is Side
 func (l Line) To(g GPS5) float64 {
-	return l.Side(g) * Distance(NewGPS5(
-		(l.P1.Latitude+l.P2.Latitude)/2,
-		(l.P1.Longitude+l.P2.Longitude)/2),
-		g)
+	p := l.Projection()
+	a, b := p.Points(l)
+	d, _ := SegmentDistance(p.Point(g), a, b)
+	return l.Side(g) * d
 }
 
+// Side 1 when g is on the left of line from P1 to P2, -1 on the right
 func (l Line) Side(g GPS5) float64 {
-	s := (g.Latitude-l.P1.Latitude)*(l.P2.Longitude-l.P1.Longitude) -
-		(g.Longitude-l.P1.Longitude)*(l.P2.Latitude-l.P1.Latitude)
-	if s >= 0 {
+	p := l.Projection()
+	a, b := p.Points(l)
+	if b.Sub(a).Cross(p.Point(g).Sub(a)) >= 0 {
 		return 1
 	}
 	return -1
@@ -93,7 +107,7 @@ func GpsWithTime(values []*telemetry.TELEM) (all []Timely) {
 		for j, value := range available {
 			all = append(all, Timely{
 				Time:  v.Time.Time.Add(time.Duration((int64(j) * delta) / int64(len(available)))),
-				Value: GPS5{GPS5: value, Accuracy: v.GpsAccuracy.Accuracy},
+				Value: GPS5{GPS5: value, Accuracy: v.GpsAccuracy.Accuracy, Fix: uint16(v.GpsFix.F)},
 			})
 		}
 	}
@@ -0,0 +1,150 @@
+package gokart
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// GPS9_SAMPLE_SIZE bytes of one GPS9 sample: latitude, longitude, altitude,
+// 2D speed, 3D speed, days since 2000, seconds since midnight as int32,
+// DOP and fix as uint16
+const GPS9_SAMPLE_SIZE = 32
+
+// gps9Epoch day 0 of GPS9 samples
+var gps9Epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// gpmfItem one key-length-value of GPMF data
+type gpmfItem struct {
+	key    string
+	kind   byte
+	size   int
+	repeat int
+	value  []byte
+}
+
+// gpmfItems split GPMF data in items, an error is returned when data is truncated
+func gpmfItems(data []byte) (items []gpmfItem, err error) {
+	for len(data) >= 8 {
+		it := gpmfItem{
+			key:    string(data[:4]),
+			kind:   data[4],
+			size:   int(data[5]),
+			repeat: int(binary.BigEndian.Uint16(data[6:8])),
+		}
+		length := it.size * it.repeat
+		padded := (length + 3) &^ 3
+		if 8+length > len(data) {
+			err = fmt.Errorf("truncated GPMF %s", it.key)
+			return
+		}
+		if 8+padded > len(data) {
+			// last item without padding
+			padded = length
+		}
+		it.value = data[8 : 8+length]
+		items = append(items, it)
+		data = data[8+padded:]
+	}
+	return
+}
+
+// scales of a SCAL item, one per sample element or one for all
+func (it gpmfItem) scales() (scales []float64) {
+	for i := range it.repeat {
+		v := it.value[i*it.size : (i+1)*it.size]
+		switch it.kind {
+		case 's':
+			scales = append(scales, float64(int16(binary.BigEndian.Uint16(v))))
+		case 'S':
+			scales = append(scales, float64(binary.BigEndian.Uint16(v)))
+		case 'l':
+			scales = append(scales, float64(int32(binary.BigEndian.Uint32(v))))
+		case 'L':
+			scales = append(scales, float64(binary.BigEndian.Uint32(v)))
+		}
+	}
+	return
+}
+
+// GPS9WithTime GPS9 samples of raw gpmd stream with their own time, as GPS5
+// values with fix and DOP, empty when camera does not record GPS9
+func GPS9WithTime(gpmd []byte) (all []Timely, err error) {
+	all = make([]Timely, 0)
+	// stream may be cut at end of video, complete devices are still read
+	devices, _ := gpmfItems(gpmd)
+	for _, device := range devices {
+		if device.key != "DEVC" || device.kind != 0 {
+			continue
+		}
+		streams, derr := gpmfItems(device.value)
+		if derr != nil {
+			return all, derr
+		}
+		for _, stream := range streams {
+			if stream.key != "STRM" || stream.kind != 0 {
+				continue
+			}
+			var samples []Timely
+			if samples, err = gps9Stream(stream.value); err != nil {
+				return
+			}
+			all = append(all, samples...)
+		}
+	}
+	return
+}
+
+// gps9Stream samples of one STRM, nil when it is not GPS9
+func gps9Stream(data []byte) (samples []Timely, err error) {
+	items, err := gpmfItems(data)
+	if err != nil {
+		return
+	}
+	var scales []float64
+	for _, it := range items {
+		switch it.key {
+		case "SCAL":
+			scales = it.scales()
+		case "GPS9":
+			if it.size != GPS9_SAMPLE_SIZE {
+				return nil, fmt.Errorf("unexpected GPS9 sample size %d", it.size)
+			}
+			if len(scales) == 1 {
+				for len(scales) < 9 {
+					scales = append(scales, scales[0])
+				}
+			}
+			if len(scales) != 9 {
+				return nil, fmt.Errorf("GPS9 needs 9 scales, %d found", len(scales))
+			}
+			for i := range scales {
+				if scales[i] == 0 {
+					scales[i] = 1
+				}
+			}
+			for i := range it.repeat {
+				samples = append(samples, gps9Sample(it.value[i*it.size:(i+1)*it.size], scales))
+			}
+		}
+	}
+	return
+}
+
+// gps9Sample one scaled sample, DOP is stored as accuracy like GPSP of GPS5 stream
+func gps9Sample(v []byte, scales []float64) Timely {
+	value := func(i int) float64 {
+		return float64(int32(binary.BigEndian.Uint32(v[i*4:]))) / scales[i]
+	}
+	var g GPS5
+	g.Latitude = value(0)
+	g.Longitude = value(1)
+	g.Altitude = value(2)
+	g.Speed = value(3)
+	g.Speed3D = value(4)
+	dop := float64(binary.BigEndian.Uint16(v[28:])) / scales[7]
+	g.Accuracy = uint16(dop*100 + 0.5)
+	g.Fix = binary.BigEndian.Uint16(v[30:])
+	t := gps9Epoch.AddDate(0, 0, int(value(5))).Add(time.Duration(value(6) * float64(time.Second)))
+	return Timely{Time: t, Value: g}
+}
@@ -0,0 +1,62 @@
+package gokart
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// gps9Bytes raw GPS9 sample at ms of 2024-09-14, 3D fix with DOP 1.53
+func gps9Bytes(lat, lon float64, ms int32) []byte {
+	v := make([]byte, GPS9_SAMPLE_SIZE)
+	for i, x := range []int32{int32(lat * 1e7), int32(lon * 1e7), 52000, 12500, 1260, 9023, ms} {
+		binary.BigEndian.PutUint32(v[i*4:], uint32(x))
+	}
+	binary.BigEndian.PutUint16(v[28:], 153)
+	binary.BigEndian.PutUint16(v[30:], 3)
+	return v
+}
+
+// gps9Strm content of a GPS9 STRM with its scales
+func gps9Strm(samples ...[]byte) []byte {
+	scal := make([]byte, 36)
+	for i, s := range []uint32{10000000, 10000000, 1000, 1000, 100, 1, 1000, 100, 1} {
+		binary.BigEndian.PutUint32(scal[i*4:], s)
+	}
+	strm := append(klv("STNM", 'c', []byte("GPS (Lat., Long., Alt., 2D, 3D, days, secs, DOP, fix)")), klvs("SCAL", 'L', 4, scal)...)
+	return append(strm, klvs("GPS9", '?', GPS9_SAMPLE_SIZE, slices.Concat(samples...))...)
+}
+
+func TestGPS9WithTime(t *testing.T) {
+	strm := gps9Strm(gps9Bytes(47.3901, -1.1601, 40000000), gps9Bytes(47.3902, -1.1602, 40000100))
+	gpmd := klv("DEVC", 0, append(klv("DVNM", 'c', []byte("HERO11 Black")), klv("STRM", 0, strm)...))
+
+	all, err := GPS9WithTime(gpmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("%d samples should be 2", len(all))
+	}
+	g := all[0].Value.(GPS5)
+	if math.Abs(g.Latitude-47.3901) > 1e-7 || math.Abs(g.Longitude+1.1601) > 1e-7 || g.Altitude != 52 || g.Speed != 12.5 || g.Speed3D != 12.6 {
+		t.Errorf("wrong sample %+v", g)
+	}
+	if g.Fix != 3 || g.DOP() != 1.53 {
+		t.Errorf("wrong fix %d or DOP %f", g.Fix, g.DOP())
+	}
+	start := time.Date(2024, 9, 14, 11, 6, 40, 0, time.UTC)
+	if !all[0].Time.Equal(start) || all[1].Time.Sub(all[0].Time) != 100*time.Millisecond {
+		t.Errorf("wrong times %s %s", all[0].Time, all[1].Time)
+	}
+
+	// packet cut at end of video is ignored
+	if all, err = GPS9WithTime(gpmd[:len(gpmd)-10]); err != nil || len(all) != 0 {
+		t.Errorf("truncated packet gives %d samples, %v", len(all), err)
+	}
+	if all, err = GPS9WithTime(klv("DEVC", 0, strm[:len(strm)-10])); err == nil {
+		t.Errorf("truncated stream not detected, %d samples", len(all))
+	}
+}
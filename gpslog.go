@@ -0,0 +1,256 @@
+package gokart
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ReadNMEA GPS5 samples of a NMEA log, sentences of each epoch are merged,
+// accuracy is HDOP×100 like GoPro GPS
+func ReadNMEA(r io.Reader) (gps []Timely, err error) {
+	err = ReadNMEAStream(r, func(g Timely) error {
+		gps = append(gps, g)
+		return nil
+	})
+	if err == nil && len(gps) == 0 {
+		err = errors.New("no valid NMEA fix")
+	}
+	return
+}
+
+// CSVFormat columns of a GPS log, each field lists accepted header names
+// compared without case, first one found is used
+type CSVFormat struct {
+	// Comma separator, ',' when 0
+	Comma     rune
+	Time      []string
+	Latitude  []string
+	Longitude []string
+	// Altitude, Speed, Speed3D, HDOP and Accuracy are optional, when only
+	// one speed is found it is used for both
+	Altitude []string
+	Speed    []string
+	Speed3D  []string
+	HDOP     []string
+	// Accuracy like GoPro GPS5, used when there is no HDOP
+	Accuracy []string
+	// TimeLayout of time.Parse, or "unix", "unixms" for seconds or milliseconds since epoch
+	TimeLayout string
+	// SpeedFactor speed column to m/s, like 1/3.6 for km/h, 1 when 0
+	SpeedFactor float64
+}
+
+// DefaultCSVFormat reads gokart CSV exports and most loggers with usual column names
+var DefaultCSVFormat = CSVFormat{
+	Time:       []string{"time", "timestamp", "utc", "datetime"},
+	Latitude:   []string{"latitude", "lat"},
+	Longitude:  []string{"longitude", "lon", "lng", "long"},
+	Altitude:   []string{"altitude", "alt", "elevation", "ele"},
+	Speed:      []string{"speed", "speed2d"},
+	Speed3D:    []string{"speed3d"},
+	HDOP:       []string{"hdop"},
+	Accuracy:   []string{"accuracy"},
+	TimeLayout: time.RFC3339Nano,
+}
+
+// Set field of format from a sidecar key: comma, time, latitude, longitude,
+// altitude, speed, speed3d, hdop or accuracy with column names separated
+// by |, time_layout or speed_factor
+func (f *CSVFormat) Set(key, value string) (err error) {
+	columns := map[string]*[]string{
+		"time": &f.Time, "latitude": &f.Latitude, "longitude": &f.Longitude, "altitude": &f.Altitude,
+		"speed": &f.Speed, "speed3d": &f.Speed3D, "hdop": &f.HDOP, "accuracy": &f.Accuracy,
+	}
+	switch key = strings.ToLower(key); key {
+	case "comma":
+		if utf8.RuneCountInString(value) != 1 {
+			return fmt.Errorf("comma should be one character %q", value)
+		}
+		f.Comma, _ = utf8.DecodeRuneInString(value)
+	case "time_layout":
+		f.TimeLayout = value
+	case "speed_factor":
+		if f.SpeedFactor, err = strconv.ParseFloat(value, 64); err != nil || f.SpeedFactor <= 0 {
+			return fmt.Errorf("wrong speed factor %q", value)
+		}
+	default:
+		names, ok := columns[key]
+		if !ok {
+			return fmt.Errorf("unknown csv key %q", key)
+		}
+		*names = strings.Split(value, "|")
+	}
+	return
+}
+
+// column index of first name found in header, -1 if none
+func column(header []string, names []string) int {
+	for _, name := range names {
+		if i := slices.IndexFunc(header, func(h string) bool {
+			return strings.EqualFold(strings.TrimSpace(h), name)
+		}); i >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseTime of a log according to layout
+func (f CSVFormat) parseTime(s string) (t time.Time, err error) {
+	switch f.TimeLayout {
+	case "unix", "unixms":
+		v, perr := strconv.ParseFloat(s, 64)
+		if perr != nil {
+			return t, perr
+		}
+		// microseconds keep float precision
+		if f.TimeLayout == "unixms" {
+			return time.UnixMicro(int64(math.Round(v * 1e3))).UTC(), nil
+		}
+		return time.UnixMicro(int64(math.Round(v * 1e6))).UTC(), nil
+	}
+	return time.Parse(f.TimeLayout, s)
+}
+
+// ReadCSV GPS5 samples of a CSV log with a header line, lines without
+// position are skipped, samples must be sorted by time
+func ReadCSV(r io.Reader, f CSVFormat) (gps []Timely, err error) {
+	cr := csv.NewReader(r)
+	if f.Comma != 0 {
+		cr.Comma = f.Comma
+	}
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("no CSV header:%w", err)
+	}
+	cols := make(map[string]int)
+	for name, names := range map[string][]string{
+		"time": f.Time, "latitude": f.Latitude, "longitude": f.Longitude, "altitude": f.Altitude,
+		"speed": f.Speed, "speed3d": f.Speed3D, "hdop": f.HDOP, "accuracy": f.Accuracy,
+	} {
+		cols[name] = column(header, names)
+	}
+	for _, name := range []string{"time", "latitude", "longitude"} {
+		if cols[name] < 0 {
+			return nil, fmt.Errorf("no %s column in %v", name, header)
+		}
+	}
+	speedFactor := f.SpeedFactor
+	if speedFactor == 0 {
+		speedFactor = 1
+	}
+	for line := 2; ; line++ {
+		record, rerr := cr.Read()
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return nil, rerr
+		}
+		value := func(name string) (v float64, ok bool, err error) {
+			i := cols[name]
+			if i < 0 || i >= len(record) || strings.TrimSpace(record[i]) == "" {
+				return
+			}
+			v, err = strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+			if err != nil {
+				err = fmt.Errorf("line %d %s:%w", line, name, err)
+			}
+			return v, err == nil, err
+		}
+		lat, okLat, lerr := value("latitude")
+		lon, okLon, oerr := value("longitude")
+		if err = errors.Join(lerr, oerr); err != nil {
+			return
+		}
+		if !okLat || !okLon {
+			continue
+		}
+		t, terr := f.parseTime(strings.TrimSpace(record[cols["time"]]))
+		if terr != nil {
+			return nil, fmt.Errorf("line %d time:%w", line, terr)
+		}
+		g := NewGPS5(lat, lon)
+		g.Accuracy = 9999
+		if g.Altitude, _, err = value("altitude"); err != nil {
+			return
+		}
+		speed, okSpeed, serr := value("speed")
+		speed3D, okSpeed3D, s3err := value("speed3d")
+		if err = errors.Join(serr, s3err); err != nil {
+			return
+		}
+		if !okSpeed {
+			speed = speed3D
+		}
+		if !okSpeed3D {
+			speed3D = speed
+		}
+		g.Speed, g.Speed3D = speed*speedFactor, speed3D*speedFactor
+		if hdop, ok, herr := value("hdop"); herr != nil {
+			return nil, herr
+		} else if ok {
+			g.Accuracy = uint16(min(hdop*100, 9999))
+		} else if acc, ok, aerr := value("accuracy"); aerr != nil {
+			return nil, aerr
+		} else if ok {
+			g.Accuracy = uint16(min(acc, 9999))
+		}
+		if n := len(gps); n > 0 && t.Before(gps[n-1].Time) {
+			return nil, fmt.Errorf("line %d is before previous one", line)
+		}
+		gps = append(gps, Timely{Time: t, Value: g})
+	}
+	if len(gps) == 0 {
+		err = errors.New("no position in CSV")
+	}
+	return
+}
+
+// GPS_LOG_EXTENSIONS files read as GPS logs instead of GoPro videos
+var GPS_LOG_EXTENSIONS = []string{".nmea", ".nma", ".csv"}
+
+// IsGPSLog filename is a NMEA or CSV log
+func IsGPSLog(filename string) bool {
+	return slices.Contains(GPS_LOG_EXTENSIONS, strings.ToLower(filepath.Ext(filename)))
+}
+
+// loadGPSLog session of a NMEA or CSV log, with sidecar metadata and CSV
+// format, there is no ACCL
+func loadGPSLog(filename string) (s *Session, err error) {
+	s = &Session{Filename: filename}
+	if s.Meta, err = ReadSidecar(filename); err != nil {
+		return
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(filename), ".csv") {
+		format := DefaultCSVFormat
+		if s.Meta.CSV != nil {
+			format = *s.Meta.CSV
+		}
+		s.GPS, err = ReadCSV(f, format)
+	} else {
+		s.GPS, err = ReadNMEA(f)
+	}
+	if err != nil {
+		err = fmt.Errorf("unable to read GPS log %s:%w", filename, err)
+		return
+	}
+	err = s.countLaps()
+	return
+}
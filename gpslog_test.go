@@ -0,0 +1,139 @@
+package gokart
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// nmeaLog RMC and GGA sentences of each GPS sample
+func nmeaLog(gps []Timely) string {
+	var b strings.Builder
+	coord := func(v float64, pos, neg string) (string, string) {
+		h := pos
+		if v < 0 {
+			v, h = -v, neg
+		}
+		d := math.Floor(v)
+		return fmt.Sprintf("%02.0f%08.5f", d, (v-d)*60), h
+	}
+	for _, t := range gps {
+		g := t.Value.(GPS5)
+		lat, ns := coord(g.Latitude, "N", "S")
+		lon, ew := coord(g.Longitude, "E", "W")
+		hms := t.Time.UTC().Format("150405.00")
+		fmt.Fprintln(&b, nmeaLine(fmt.Sprintf("GPRMC,%s,A,%s,%s,0%s,%s,%.3f,0.0,%s,,,A",
+			hms, lat, ns, lon, ew, g.Speed3D/KNOT, t.Time.UTC().Format("020106"))))
+		fmt.Fprintln(&b, nmeaLine(fmt.Sprintf("GPGGA,%s,%s,%s,0%s,%s,1,10,0.8,%.1f,M,,M,,", hms, lat, ns, lon, ew, g.Altitude)))
+	}
+	return b.String()
+}
+
+func TestReadNMEA(t *testing.T) {
+	s := circleSession([]float64{10, 12, 11, 12.5})
+	gps, err := ReadNMEA(strings.NewReader(nmeaLog(s.GPS)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gps) != len(s.GPS) {
+		t.Fatalf("read %d samples should be %d", len(gps), len(s.GPS))
+	}
+	if d := Distance(gps[100].Value.(GPS5), s.GPS[100].Value.(GPS5)); d > 0.05 {
+		t.Errorf("position is %fm away", d)
+	}
+	log := &Session{GPS: gps}
+	log.CountLaps(s.Track)
+	if len(log.Laps.CompleteLaps()) != 3 || log.Laps.Best() != s.Laps.Best() {
+		t.Errorf("found %d laps best %d", len(log.Laps.CompleteLaps()), log.Laps.Best())
+	}
+	if g := gps[0].Value.(GPS5); g.Accuracy != 80 || math.Abs(g.Speed-10) > 0.01 {
+		t.Errorf("wrong first sample %+v", g)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	s := circleSession([]float64{10, 12})
+	for i := range s.GPS {
+		g := s.GPS[i].Value.(GPS5)
+		g.Speed = g.Speed3D - 0.5
+		s.GPS[i].Value = g
+	}
+	var b bytes.Buffer
+	if err := s.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	gps, err := ReadCSV(&b, DefaultCSVFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, want := gps[10].Value.(GPS5), s.GPS[10].Value.(GPS5)
+	if len(gps) != len(s.GPS) || !gps[10].Time.Equal(s.GPS[10].Time) || g.Latitude != want.Latitude || g.Longitude != want.Longitude || g.Speed != want.Speed || g.Speed3D != want.Speed3D {
+		t.Errorf("export is not read back %+v", gps[10])
+	}
+	format := CSVFormat{
+		Comma: ';', Time: []string{"ts"}, Latitude: []string{"Lat"}, Longitude: []string{"Lon"},
+		Speed: []string{"kmh"}, HDOP: []string{"HDOP"}, TimeLayout: "unixms", SpeedFactor: 1 / 3.6,
+	}
+	gps, err = ReadCSV(strings.NewReader("ts;Lat;Lon;kmh;HDOP\n1726312320100;47.1;0.2;36;1.2\n1726312320200;;;;\n"), format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gps) != 1 || gps[0].Time.UnixMilli() != 1726312320100 {
+		t.Fatalf("wrong samples %+v", gps)
+	}
+	if g := gps[0].Value.(GPS5); g.Latitude != 47.1 || math.Abs(g.Speed-10) > 1e-9 || g.Speed3D != g.Speed || g.Accuracy != 120 {
+		t.Errorf("wrong sample %+v", g)
+	}
+	if _, err = ReadCSV(strings.NewReader("a,b\n1,2\n"), DefaultCSVFormat); err == nil {
+		t.Error("missing columns should fail")
+	}
+}
+
+func TestLoadGPSLog(t *testing.T) {
+	s := circleSession([]float64{10, 12})
+	filename := filepath.Join(t.TempDir(), "logger.nmea")
+	if err := os.WriteFile(filename, []byte(nmeaLog(s.GPS)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// circle is not a known track
+	log, err := LoadSession(filename)
+	if !errors.Is(err, ErrUnknownTrack) || len(log.GPS) != len(s.GPS) || len(log.ACCL) != 0 {
+		t.Fatalf("read %d samples should be %d %v", len(log.GPS), len(s.GPS), err)
+	}
+	tracks := TheWorld.Tracks
+	TheWorld.Tracks = append(slices.Clone(tracks), s.Track)
+	t.Cleanup(func() { TheWorld.Tracks = tracks })
+	if log, err = LoadSession(filename); err != nil || log.Track != s.Track || log.Laps.Best() != s.Laps.Best() {
+		t.Errorf("circle track should be found %v", err)
+	}
+	// logger layout given in sidecar
+	var b strings.Builder
+	b.WriteString("ts;Lat;Lon;kmh\n")
+	for _, g := range s.GPS {
+		v := g.Value.(GPS5)
+		fmt.Fprintf(&b, "%d;%f;%f;%f\n", g.Time.UnixMilli(), v.Latitude, v.Longitude, v.Speed3D*3.6)
+	}
+	filename = filepath.Join(t.TempDir(), "logger.csv")
+	sidecar := "driver: Alice\ncsv_comma: \";\"\ncsv_time: ts\ncsv_latitude: Lat\ncsv_longitude: Lon\ncsv_speed: kmh|speed\ncsv_time_layout: unixms\ncsv_speed_factor: 0.2777777777777778\n"
+	if err = os.WriteFile(filename, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(strings.TrimSuffix(filename, ".csv")+".yaml", []byte(sidecar), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if log, err = LoadSession(filename); err != nil {
+		t.Fatal(err)
+	}
+	if len(log.GPS) != len(s.GPS) || log.Track != s.Track || log.Meta.Driver != "Alice" || math.Abs(log.GPS[10].Value.(GPS5).Speed-10) > 1e-3 {
+		t.Errorf("wrong CSV session %d samples %+v", len(log.GPS), log.Meta)
+	}
+	if err = log.Meta.setCSV("speed_factor", "-1"); err == nil {
+		t.Error("negative speed factor should fail")
+	}
+}
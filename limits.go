@@ -0,0 +1,261 @@
+package gokart
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"time"
+)
+
+// ErrNoBoundaries track has no asphalt edges defined
+var ErrNoBoundaries = errors.New("track has no boundaries")
+
+// Boundaries asphalt edges of a track, either inner and outer closed
+// polylines drawn on map or a closed centerline with track width in metres
+type Boundaries struct {
+	Inner      []GPS5  `json:"inner,omitempty"`
+	Outer      []GPS5  `json:"outer,omitempty"`
+	Centerline []GPS5  `json:"centerline,omitempty"`
+	Width      float64 `json:"width,omitempty"`
+}
+
+// Corner named place of a track, excursions are reported at closest corner
+type Corner struct {
+	Name     string `json:"name"`
+	Position GPS5   `json:"position"`
+}
+
+// Validate enough points to build edges
+func (b Boundaries) Validate() error {
+	if len(b.Inner) > 0 || len(b.Outer) > 0 {
+		if len(b.Inner) < 3 || len(b.Outer) < 3 {
+			return errors.New("inner and outer boundaries need at least 3 points")
+		}
+		return nil
+	}
+	if len(b.Centerline) < 3 {
+		return errors.New("centerline needs at least 3 points")
+	}
+	if b.Width <= 0 {
+		return errors.New("centerline needs a positive width")
+	}
+	return nil
+}
+
+// trackEdges boundaries in tangent plane of track
+type trackEdges struct {
+	proj  Projection
+	inner Polygon
+	outer Polygon
+}
+
+// edges of track in tangent plane around start line
+func (t Track) edges() (e trackEdges, err error) {
+	if t.Boundaries == nil {
+		return e, ErrNoBoundaries
+	}
+	b := *t.Boundaries
+	if err = b.Validate(); err != nil {
+		return
+	}
+	e.proj = t.Start.Projection()
+	project := func(points []GPS5) (pg Polygon) {
+		for _, g := range points {
+			pg = append(pg, e.proj.Point(g))
+		}
+		return
+	}
+	if len(b.Inner) > 0 {
+		e.inner, e.outer = project(b.Inner), project(b.Outer)
+	} else {
+		center := project(b.Centerline)
+		e.inner, e.outer = center.Offset(b.Width/2), center.Offset(-b.Width/2)
+	}
+	if math.Abs(e.inner.Area()) > math.Abs(e.outer.Area()) {
+		e.inner, e.outer = e.outer, e.inner
+	}
+	return
+}
+
+// Offset polygon moved by d metres on the left of its direction,
+// corners are mitered and limited to 3 times d
+func (pg Polygon) Offset(d float64) (offset Polygon) {
+	n := len(pg)
+	offset = make(Polygon, n)
+	for i := range pg {
+		prev, next := pg[(i+n-1)%n], pg[(i+1)%n]
+		d1, d2 := pg[i].Sub(prev), next.Sub(pg[i])
+		n1 := Point{-d1.N, d1.E}.Scale(1 / d1.Norm())
+		n2 := Point{-d2.N, d2.E}.Scale(1 / d2.Norm())
+		miter := n1.Add(n2)
+		if miter.Norm() == 0 {
+			// back and forth, keep first normal
+			miter = n1
+		}
+		miter = miter.Scale(1 / miter.Norm())
+		offset[i] = pg[i].Add(miter.Scale(d / max(miter.Dot(n1), 1./3)))
+	}
+	return
+}
+
+// closed polyline of polygon
+func (pg Polygon) closed() Polyline {
+	return append(Polyline(pg), pg[0])
+}
+
+// outside metres beyond track edge, 0 on asphalt
+func (e trackEdges) outside(p Point) (d float64, side string) {
+	if !e.outer.Contains(p) {
+		_, d, _ = e.outer.closed().Closest(p)
+		return d, "outer"
+	}
+	if e.inner.Contains(p) {
+		_, d, _ = e.inner.closed().Closest(p)
+		return d, "inner"
+	}
+	return
+}
+
+// LimitsConfig how track limits are checked
+type LimitsConfig struct {
+	// Tolerance metres beyond edge allowed, GPS is not more precise
+	Tolerance float64
+	// MinDuration shorter excursions are ignored
+	MinDuration time.Duration
+	// MaxExcursions allowed per lap before it is invalidated
+	MaxExcursions int
+}
+
+// DefaultLimitsConfig any excursion of more than 1.5m during 200ms invalidates lap
+var DefaultLimitsConfig = LimitsConfig{
+	Tolerance:   1.5,
+	MinDuration: 200 * time.Millisecond,
+}
+
+// Excursion kart out of track edges
+type Excursion struct {
+	// Lap -1 before first start line
+	Lap    int    `json:"lap"`
+	Corner string `json:"corner"`
+	// Side "inner" or "outer" edge
+	Side  string `json:"side"`
+	Start Timely `json:"start"`
+	Stop  Timely `json:"stop"`
+	// Distance farthest position beyond edge in metres, at Worst
+	Distance float64 `json:"distance"`
+	Worst    Timely  `json:"worst"`
+}
+
+// Duration time out of track
+func (e Excursion) Duration() time.Duration {
+	return e.Stop.Time.Sub(e.Start.Time)
+}
+
+func (e Excursion) String() string {
+	return fmt.Sprintf("lap %02d %s %s edge at %s for %s, %.1fm out",
+		e.Lap, e.Corner, e.Side, e.Start.Time.Format("15:04:05.00"), DurationToChrono(e.Duration()), e.Distance)
+}
+
+// LimitsReport track limits analysis of a session
+type LimitsReport struct {
+	Excursions []Excursion `json:"excursions"`
+	// Invalidated complete laps with too many excursions
+	Invalidated []int `json:"invalidated"`
+}
+
+// sectorAt sector of lap at t, 0 for first one
+func (l LapCounter) sectorAt(lap int, t time.Time) (sector int) {
+	if lap < 0 || lap >= len(l.laps) {
+		return
+	}
+	for i, start := range l.laps[lap] {
+		if !start.IsZero() && !t.Before(start) {
+			sector = i
+		}
+	}
+	return
+}
+
+// closestCorner name of corner closest to g, empty when track has no corner
+func (t Track) closestCorner(proj Projection, g GPS5) (name string) {
+	p := proj.Point(g)
+	best := math.Inf(1)
+	for _, c := range t.Corners {
+		if d := proj.Point(c.Position).Sub(p).Norm(); d < best {
+			name, best = c.Name, d
+		}
+	}
+	return
+}
+
+// corner name of closest corner, sector name when track has no corner
+func (s *Session) corner(e trackEdges, g Timely, lap int) string {
+	name := s.Track.closestCorner(e.proj, g.Value.(GPS5))
+	if name == "" {
+		name = fmt.Sprintf("S%d", s.Laps.sectorAt(lap, g.Time)+1)
+	}
+	return name
+}
+
+// TrackLimits samples out of track edges grouped in excursions, laps with more
+// than cfg.MaxExcursions are invalidated
+func (s *Session) TrackLimits(cfg LimitsConfig) (r LimitsReport, err error) {
+	r.Excursions = make([]Excursion, 0)
+	r.Invalidated = make([]int, 0)
+	if s.Track == nil {
+		return r, ErrUnknownTrack
+	}
+	e, err := s.Track.edges()
+	if err != nil {
+		return
+	}
+	laps := s.Laps.lapOf(s.GPS)
+	var current *Excursion
+	closeExcursion := func() {
+		if current != nil && current.Duration() >= cfg.MinDuration {
+			current.Corner = s.corner(e, current.Worst, current.Lap)
+			r.Excursions = append(r.Excursions, *current)
+		}
+		current = nil
+	}
+	for i, g := range s.GPS {
+		d, side := e.outside(e.proj.Point(g.Value.(GPS5)))
+		if d <= cfg.Tolerance {
+			closeExcursion()
+			continue
+		}
+		if current != nil && (current.Side != side || current.Lap != laps[i]) {
+			closeExcursion()
+		}
+		if current == nil {
+			current = &Excursion{Lap: laps[i], Side: side, Start: g}
+		}
+		current.Stop = g
+		if d > current.Distance {
+			current.Distance, current.Worst = d, g
+		}
+	}
+	closeExcursion()
+	count := make(map[int]int)
+	for _, x := range r.Excursions {
+		count[x.Lap]++
+	}
+	for _, lap := range s.Laps.CompleteLaps() {
+		if count[lap] > cfg.MaxExcursions {
+			r.Invalidated = append(r.Invalidated, lap)
+		}
+	}
+	return
+}
+
+// DrawExcursions red circle at farthest position of each excursion
+func (t Track) DrawExcursions(img *image.RGBA, excursions []Excursion) {
+	r := img.Bounds()
+	for _, e := range excursions {
+		g := e.Worst.Value.(GPS5)
+		x, y := t.PosToXY(r, g.Latitude, g.Longitude)
+		DrawEmptyCircle(img, x, y, 10, red)
+		DrawCircle(img, x, y, 4, red)
+	}
+}
@@ -0,0 +1,70 @@
+package gokart
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTrackLimits(t *testing.T) {
+	s := circleSession([]float64{10, 12, 11, 12.5})
+	center := NewGPS5(47.0, 0.2)
+	dlat := 1 / 111320.0
+	dlon := dlat / math.Cos(center.Latitude*math.Pi/180)
+	at := func(angle, r float64) GPS5 {
+		return NewGPS5(center.Latitude+r*math.Sin(angle)*dlat, center.Longitude+r*math.Cos(angle)*dlon)
+	}
+	track := *s.Track
+	track.Boundaries = &Boundaries{Width: 8}
+	for i := range 36 {
+		track.Boundaries.Centerline = append(track.Boundaries.Centerline, at(float64(i)*math.Pi/18, 100))
+	}
+	track.Corners = []Corner{{"hairpin", at(math.Pi/2, 100)}, {"back", at(3*math.Pi/2, 100)}}
+	s.Track = &track
+
+	// 1s wide at north in lap 2 and a short one at south in lap 3
+	push := func(lap int, angle float64, d time.Duration, r float64) {
+		from := s.Laps.LapStart(lap).Add(time.Duration(angle / (2 * math.Pi) * float64(s.Laps.LapTime(lap))))
+		for i, g := range s.GPS {
+			if !g.Time.Before(from) && g.Time.Before(from.Add(d)) {
+				v := g.Value.(GPS5)
+				v.Latitude = center.Latitude + (v.Latitude-center.Latitude)*r
+				v.Longitude = center.Longitude + (v.Longitude-center.Longitude)*r
+				s.GPS[i].Value = v
+			}
+		}
+	}
+	push(2, math.Pi/2, time.Second, 1.1)
+	push(3, 3*math.Pi/2, 100*time.Millisecond, 0.9)
+
+	r, err := s.TrackLimits(DefaultLimitsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Excursions) != 1 {
+		t.Fatalf("found %d excursions should be 1: %v", len(r.Excursions), r.Excursions)
+	}
+	e := r.Excursions[0]
+	if e.Lap != 2 || e.Corner != "hairpin" || e.Side != "outer" || math.Abs(e.Distance-6) > 0.5 {
+		t.Errorf("wrong excursion %s", e)
+	}
+	if len(r.Invalidated) != 1 || r.Invalidated[0] != 2 {
+		t.Errorf("wrong invalidated laps %v", r.Invalidated)
+	}
+
+	cfg := DefaultLimitsConfig
+	cfg.MinDuration = 0
+	if r, _ = s.TrackLimits(cfg); len(r.Excursions) != 2 || r.Excursions[1].Side != "inner" || r.Excursions[1].Corner != "back" {
+		t.Errorf("short excursion not found %v", r.Excursions)
+	}
+
+	track.Corners = nil
+	if r, _ = s.TrackLimits(DefaultLimitsConfig); len(r.Excursions) != 1 || r.Excursions[0].Corner != "S1" {
+		t.Errorf("wrong sector of excursion %v", r.Excursions)
+	}
+
+	track.Boundaries = nil
+	if _, err = s.TrackLimits(DefaultLimitsConfig); err != ErrNoBoundaries {
+		t.Errorf("missing boundaries not detected %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package gokart
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// LiveEvent line crossed during a live session, durations in milliseconds
+type LiveEvent struct {
+	// Kind "start" for first start line crossing, "sector" or "lap"
+	Kind  string    `json:"kind"`
+	Track string    `json:"track"`
+	Lap   int       `json:"lap"`
+	Time  time.Time `json:"time"`
+	// Sector finished, first is 1, last one ends with lap
+	Sector     int   `json:"sector,omitempty"`
+	SectorTime int64 `json:"sectortime,omitempty"`
+	LapTime    int64 `json:"laptime,omitempty"`
+	// Delta to best lap at same line, negative when faster, 0 without best lap
+	Delta int64 `json:"delta"`
+	// Status of sector or lap sectors, 2 purple, 1 green, -1 red, 0 unknown
+	Status   int   `json:"status,omitempty"`
+	Statuses []int `json:"statuses,omitempty"`
+	Best     bool  `json:"best,omitempty"`
+}
+
+// LiveTimer lap counter fed one GPS sample at a time
+type LiveTimer struct {
+	laps    LapCounter
+	prev    Timely
+	started bool
+}
+
+// NewLiveTimer timer on track, laps are not printed
+func NewLiveTimer(track *Track) *LiveTimer {
+	l := NewLapCounter(track)
+	l.SetOutput(nil)
+	return &LiveTimer{laps: l}
+}
+
+// Laps counted so far
+func (lt *LiveTimer) Laps() LapCounter {
+	return lt.laps
+}
+
+// elapsed time from start of lap to its line i, 0 when unknown
+func (l LapCounter) elapsed(lap, i int) time.Duration {
+	if lap < 0 || lap >= len(l.laps) || l.laps[lap][0].IsZero() || l.laps[lap][i].IsZero() {
+		return 0
+	}
+	return l.laps[lap][i].Sub(l.laps[lap][0])
+}
+
+// Push next GPS sample, samples must be sorted by time
+func (lt *LiveTimer) Push(g Timely) (events []LiveEvent) {
+	if !lt.started {
+		lt.prev, lt.started = g, true
+		return
+	}
+	l := &lt.laps
+	lap, best := l.current, l.best
+	var bestTime time.Duration
+	if best >= 0 {
+		bestTime = l.LapTime(best)
+	}
+	before := slices.Clone(l.laps[lap])
+	l.Update(g.Time, lt.prev, g)
+	lt.prev = g
+	track := l.track.Name
+	if l.current != lap {
+		n := len(l.track.Sectors)
+		if l.laps[lap][0].IsZero() {
+			events = append(events, LiveEvent{Kind: "start", Track: track, Lap: l.current, Time: l.laps[l.current][0]})
+		} else {
+			d := l.LapTime(lap)
+			status := l.LapStatus(lap)
+			if n > 0 && !l.laps[lap][n].IsZero() {
+				e := LiveEvent{
+					Kind:       "sector",
+					Track:      track,
+					Lap:        lap,
+					Time:       l.laps[lap+1][0],
+					Sector:     n + 1,
+					SectorTime: l.laps[lap+1][0].Sub(l.laps[lap][n]).Milliseconds(),
+					Status:     status[n],
+				}
+				if bestTime > 0 {
+					e.Delta = (d - bestTime).Milliseconds()
+				}
+				events = append(events, e)
+			}
+			e := LiveEvent{
+				Kind:     "lap",
+				Track:    track,
+				Lap:      lap,
+				Time:     l.laps[lap+1][0],
+				LapTime:  d.Milliseconds(),
+				Statuses: status,
+				Best:     l.best == lap,
+			}
+			if bestTime > 0 {
+				e.Delta = (d - bestTime).Milliseconds()
+			}
+			events = append(events, e)
+		}
+		lap = l.current
+		before = make([]time.Time, len(l.laps[lap]))
+		before[0] = l.laps[lap][0]
+	}
+	for i := 1; i < len(l.laps[lap]); i++ {
+		if !before[i].IsZero() || l.laps[lap][i].IsZero() {
+			continue
+		}
+		e := LiveEvent{Kind: "sector", Track: track, Lap: lap, Time: l.laps[lap][i], Sector: i, Status: l.status[i-1]}
+		if !l.laps[lap][i-1].IsZero() {
+			e.SectorTime = l.laps[lap][i].Sub(l.laps[lap][i-1]).Milliseconds()
+		}
+		if d, bd := l.elapsed(lap, i), l.elapsed(best, i); d > 0 && bd > 0 {
+			e.Delta = (d - bd).Milliseconds()
+		}
+		events = append(events, e)
+	}
+	return
+}
+
+// ReadNMEAStream call fn with each fix read from r until end of stream or
+// error of fn, lines which are not valid NMEA are skipped as a stream may
+// begin in the middle of a sentence
+func ReadNMEAStream(r io.Reader, fn func(g Timely) error) (err error) {
+	var d NMEADecoder
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fix, ok, derr := d.Decode(scanner.Text())
+		if derr != nil || !ok {
+			continue
+		}
+		if err = fn(fix.Timely()); err != nil {
+			return
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	if fix, ok := d.Flush(); ok {
+		err = fn(fix.Timely())
+	}
+	return
+}
+
+// Paced fn called no faster than samples were recorded, speed times faster,
+// without waiting when speed is 0
+func Paced(speed float64, fn func(g Timely) error) func(g Timely) error {
+	var first time.Time
+	var begin time.Time
+	return func(g Timely) error {
+		if speed > 0 {
+			if first.IsZero() {
+				first, begin = g.Time, time.Now()
+			}
+			due := begin.Add(time.Duration(float64(g.Time.Sub(first)) / speed))
+			time.Sleep(time.Until(due))
+		}
+		return fn(g)
+	}
+}
+
+// LiveHub publish live events to WebSocket clients, last lap events are sent
+// to new clients
+type LiveHub struct {
+	mutex   sync.Mutex
+	clients map[*liveClient]bool
+	history []LiveEvent
+}
+
+// liveClient events waiting to be sent to one client, channel is closed when
+// client is too slow
+type liveClient struct {
+	events chan []byte
+}
+
+const (
+	// LIVE_HISTORY events kept for new clients
+	LIVE_HISTORY = 50
+	// LIVE_BUFFER events waiting for a client before it is dropped
+	LIVE_BUFFER = 2 * LIVE_HISTORY
+	// LIVE_WRITE_TIMEOUT to send one event to a client
+	LIVE_WRITE_TIMEOUT = 5 * time.Second
+)
+
+// NewLiveHub hub without client
+func NewLiveHub() *LiveHub {
+	return &LiveHub{clients: make(map[*liveClient]bool)}
+}
+
+// ServeHTTP upgrade request to a WebSocket receiving events as JSON text messages
+func (h *LiveHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+	c := &liveClient{events: make(chan []byte, LIVE_BUFFER)}
+	h.mutex.Lock()
+	for _, e := range h.history {
+		data, _ := json.Marshal(e)
+		c.events <- data
+	}
+	h.clients[c] = true
+	h.mutex.Unlock()
+	defer func() {
+		h.mutex.Lock()
+		delete(h.clients, c)
+		h.mutex.Unlock()
+	}()
+	closed := make(chan struct{})
+	go func() {
+		ws.drain()
+		close(closed)
+	}()
+	for {
+		select {
+		case data, ok := <-c.events:
+			if !ok {
+				// too slow
+				return
+			}
+			ws.conn.SetWriteDeadline(time.Now().Add(LIVE_WRITE_TIMEOUT))
+			if err = ws.WriteText(data); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// Publish event to all clients without waiting for them, clients too slow
+// to receive events are dropped
+func (h *LiveHub) Publish(e LiveEvent) (err error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.history = append(h.history, e)
+	if len(h.history) > LIVE_HISTORY {
+		h.history = h.history[len(h.history)-LIVE_HISTORY:]
+	}
+	for c := range h.clients {
+		select {
+		case c.events <- data:
+		default:
+			delete(h.clients, c)
+			close(c.events)
+		}
+	}
+	return
+}
@@ -0,0 +1,128 @@
+package gokart
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLiveTimer(t *testing.T) {
+	s := circleSession([]float64{10, 12, 11})
+	lt := NewLiveTimer(s.Track)
+	count := make(map[string]int)
+	var laps []LiveEvent
+	for _, g := range s.GPS {
+		for _, e := range lt.Push(g) {
+			count[e.Kind]++
+			if e.Kind == "lap" {
+				laps = append(laps, e)
+			}
+		}
+	}
+	// 3 sectors per lap, last lap ends with end of samples
+	if count["start"] != 1 || count["lap"] != 2 || count["sector"] != 8 {
+		t.Fatalf("wrong events %v", count)
+	}
+	for i, e := range laps {
+		if e.LapTime != s.Laps.LapTime(e.Lap).Milliseconds() {
+			t.Errorf("lap %d time is %d should be %d", e.Lap, e.LapTime, s.Laps.LapTime(e.Lap).Milliseconds())
+		}
+		if i > 0 && (!e.Best || e.Delta >= 0) {
+			t.Errorf("faster lap should be best with negative delta %+v", e)
+		}
+	}
+}
+
+// handshake send WebSocket upgrade request with key of RFC 6455 example
+func handshake(conn net.Conn) {
+	io.WriteString(conn, "GET /live HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+}
+
+func TestLiveHub(t *testing.T) {
+	hub := NewLiveHub()
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	hub.Publish(LiveEvent{Kind: "start", Lap: 1})
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	handshake(conn)
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// example of RFC 6455
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("wrong handshake %s %v", resp.Status, resp.Header)
+	}
+	read := func() (e LiveEvent) {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(r, header); err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, header[1])
+		if _, err := io.ReadFull(r, data); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &e); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	// history first, then new events
+	if e := read(); e.Kind != "start" {
+		t.Errorf("first event is %+v", e)
+	}
+	hub.Publish(LiveEvent{Kind: "lap", Lap: 1, LapTime: 62000})
+	if e := read(); e.Kind != "lap" || e.LapTime != 62000 {
+		t.Errorf("second event is %+v", e)
+	}
+}
+
+func TestLiveHubSlowClient(t *testing.T) {
+	hub := NewLiveHub()
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// client never reads
+	handshake(conn)
+	for {
+		hub.mutex.Lock()
+		n := len(hub.clients)
+		hub.mutex.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// much more than socket buffers
+	e := LiveEvent{Kind: "lap", Track: strings.Repeat("a", 10000)}
+	start := time.Now()
+	for range 1000 {
+		if err = hub.Publish(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("publishing waited for client %s", d)
+	}
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	if len(hub.clients) != 0 {
+		t.Error("slow client should be dropped")
+	}
+}
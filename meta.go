@@ -64,14 +64,30 @@ func GetStreamsCodecTag(filename string) (m map[int]string, err error) {
 }
 
 func ReadTelemetry(filename string, index int) (values []*telemetry.TELEM, err error) {
-	gpmd := bytes.NewBuffer(nil)
+	gpmd, err := ReadStream(filename, index)
+	if err != nil {
+		return
+	}
+	return ParseTelemetry(gpmd)
+}
+
+// ReadStream raw content of stream index
+func ReadStream(filename string, index int) (data []byte, err error) {
+	buffer := bytes.NewBuffer(nil)
 	if err = ffmpeg.Input(filename).
 		Get(strconv.Itoa(index)).
 		Output("pipe:", ffmpeg.KwArgs{"codec": "copy", "format": "rawvideo"}).
-		WithOutput(gpmd, os.Stdout).
+		WithOutput(buffer, os.Stdout).
 		Run(); err != nil {
 		return
 	}
+	data = buffer.Bytes()
+	return
+}
+
+// ParseTelemetry GPMF packets of raw gpmd stream
+func ParseTelemetry(data []byte) (values []*telemetry.TELEM, err error) {
+	gpmd := bytes.NewReader(data)
 	values = make([]*telemetry.TELEM, 0)
 	for {
 		t, terr := telemetry.Read(gpmd)
@@ -122,19 +138,36 @@ func Interpolate(t time.Time, a, b Timely) (c Timely, err error) {
 		gps.Altitude = w1*aVal.Altitude + w2*bVal.Altitude
 		gps.Speed = w1*aVal.Speed + w2*bVal.Speed
 		gps.Speed3D = w1*aVal.Speed3D + w2*bVal.Speed3D
-		gps.Accuracy = aVal.Accuracy
-		if bVal.Accuracy > aVal.Accuracy {
-			gps.Accuracy = bVal.Accuracy
-		}
+		gps.Accuracy = max(aVal.Accuracy, bVal.Accuracy)
+		gps.Fix = min(aVal.Fix, bVal.Fix)
 		c.Value = gps
+	case ACCL:
+		bVal, ok := b.Value.(ACCL)
+		if !ok {
+			err = fmt.Errorf("can't interpolate between differente types (%T and %T)", a.Value, b.Value)
+			return
+		}
+		c.Time = t
+		c.Value = ACCL{X: w1*aVal.X + w2*bVal.X, Y: w1*aVal.Y + w2*bVal.Y, Z: w1*aVal.Z + w2*bVal.Z}
+	case float64:
+		bVal, ok := b.Value.(float64)
+		if !ok {
+			err = fmt.Errorf("can't interpolate between differente types (%T and %T)", a.Value, b.Value)
+			return
+		}
+		c.Time = t
+		c.Value = w1*aVal + w2*bVal
 	default:
-		err = fmt.Errorf("%T interpolation not yet implemented", a.Value)
+		err = fmt.Errorf("%T interpolation not yet implemented, use Resample", a.Value)
 	}
 	return
 }
 
-// GetVideoStartTime, clearly wrong, use first telemery time instead
+// GetVideoStartTime, clearly wrong, use first telemery time instead,
+// creation time is read according to camera quirks
 func GetVideoStartTime(filename string) (start time.Time, err error) {
+	camera, _ := ReadCamera(filename)
+	quirks := QuirksFor(camera)
 	data, err := ffmpeg.Probe(filename, nil)
 	if err != nil {
 		err = fmt.Errorf("error probing: %v", filename)
@@ -188,16 +221,9 @@ func GetVideoStartTime(filename string) (start time.Time, err error) {
 		if !ok {
 			continue
 		}
-		// TODO find location in Meta data
-		var location *time.Location
-		if location, err = time.LoadLocation("Europe/Paris"); err != nil {
-			return
-		}
-		if start, err = time.Parse(time.RFC3339, s); err != nil {
+		if start, err = quirks.creationTime(s); err != nil {
 			return
 		}
-		// Hard change as string is wrong on my GoPro
-		start = time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), location)
 		c, ok = tags["timecode"]
 		if !ok {
 			continue
@@ -213,7 +239,7 @@ func GetVideoStartTime(filename string) (start time.Time, err error) {
 				err = fmt.Errorf("unable to parse image position in timecode %s:%s", s, perr)
 				return
 			}
-			c, ok = tags["r_frame_rate"]
+			c, ok = m["r_frame_rate"]
 			if !ok {
 				continue
 			}
@@ -221,25 +247,36 @@ func GetVideoStartTime(filename string) (start time.Time, err error) {
 			if !ok {
 				continue
 			}
-			switch s {
-			case "25/1":
-				if pos < 0 || pos > 24 {
-					err = fmt.Errorf("image pos for r_frame_rate %s should be in [0 24] we have:%d", s, pos)
-					return
-				}
-				start = start.Add((time.Duration(pos) * time.Second) / time.Duration(25))
-				err = nil
-				return
-			default:
+			// 25/1 in PAL, 30000/1001 in NTSC
+			var num, den int
+			if _, serr := fmt.Sscanf(s, "%d/%d", &num, &den); serr != nil || num <= 0 || den <= 0 {
 				err = fmt.Errorf("unknown r_frame_rate %s", s)
 				return
 			}
+			// timecode frames are counted on rounded rate
+			rate := (num + den/2) / den
+			if pos < 0 || pos >= rate {
+				err = fmt.Errorf("image pos for r_frame_rate %s should be in [0 %d] we have:%d", s, rate-1, pos)
+				return
+			}
+			start = start.Add((time.Duration(pos) * time.Second) / time.Duration(rate))
+			err = nil
+			return
 		}
 	}
 	return
 }
 
 func ReadGoProTelemetry(filename string) (values []*telemetry.TELEM, err error) {
+	gpmd, err := ReadGoProStream(filename)
+	if err != nil {
+		return
+	}
+	return ParseTelemetry(gpmd)
+}
+
+// ReadGoProStream raw gpmd stream of a GoPro video
+func ReadGoProStream(filename string) (gpmd []byte, err error) {
 	var m map[int]string
 	if m, err = GetStreamsCodecTag(filename); err != nil {
 		return
@@ -255,7 +292,7 @@ func ReadGoProTelemetry(filename string) (values []*telemetry.TELEM, err error)
 		err = fmt.Errorf("unable to find gpmd stream")
 		return
 	}
-	values, err = ReadTelemetry(filename, gpmdIndex)
+	gpmd, err = ReadStream(filename, gpmdIndex)
 	return
 }
 
@@ -292,3 +329,104 @@ func FindIndex(t time.Time, all []Timely) (index int) {
 	index = dichotomy(t, all, 0, len(all)-1)
 	return
 }
+
+// VideoInfo main video stream characteristics
+type VideoInfo struct {
+	Index    int           `json:"index"`
+	Codec    string        `json:"codec"`
+	Width    int           `json:"width"`
+	Height   int           `json:"height"`
+	RateNum  int           `json:"ratenum"`
+	RateDen  int           `json:"rateden"`
+	Frames   int           `json:"frames"`
+	Duration time.Duration `json:"duration"`
+	HasAudio bool          `json:"hasaudio"`
+}
+
+// FrameRate in frames per second
+func (v VideoInfo) FrameRate() float64 {
+	if v.RateDen == 0 {
+		return 0
+	}
+	return float64(v.RateNum) / float64(v.RateDen)
+}
+
+// FrameTime position of frame n from video start
+func (v VideoInfo) FrameTime(n int) time.Duration {
+	if v.RateNum == 0 {
+		return 0
+	}
+	return time.Duration(int64(n) * int64(v.RateDen) * time.Second.Nanoseconds() / int64(v.RateNum))
+}
+
+// FrameAt frame number displayed at d from video start
+func (v VideoInfo) FrameAt(d time.Duration) int {
+	if v.RateDen == 0 {
+		return 0
+	}
+	return int(d.Nanoseconds() * int64(v.RateNum) / (int64(v.RateDen) * time.Second.Nanoseconds()))
+}
+
+// GetVideoInfo probe first video stream size, frame rate and duration
+func GetVideoInfo(filename string) (info VideoInfo, err error) {
+	probejson, perr := ffmpeg.Probe(filename)
+	if perr != nil {
+		err = fmt.Errorf("probe access:%s", perr)
+		return
+	}
+	var probe struct {
+		Streams []struct {
+			Index     int    `json:"index"`
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+			FrameRate string `json:"r_frame_rate"`
+			NbFrames  string `json:"nb_frames"`
+			Duration  string `json:"duration"`
+		} `json:"streams"`
+	}
+	if err = json.Unmarshal([]byte(probejson), &probe); err != nil {
+		err = fmt.Errorf("cannot unmarshal json probe:%s", err)
+		return
+	}
+	found := false
+	for _, s := range probe.Streams {
+		switch s.CodecType {
+		case "audio":
+			info.HasAudio = true
+		case "video":
+			if found {
+				continue
+			}
+			found = true
+			info.Index = s.Index
+			info.Codec = s.CodecName
+			info.Width = s.Width
+			info.Height = s.Height
+			if _, err = fmt.Sscanf(s.FrameRate, "%d/%d", &info.RateNum, &info.RateDen); err != nil {
+				err = fmt.Errorf("unable to parse frame rate %s:%s", s.FrameRate, err)
+				return
+			}
+			// both are optional
+			info.Frames, _ = strconv.Atoi(s.NbFrames)
+			if seconds, perr := strconv.ParseFloat(s.Duration, 64); perr == nil {
+				info.Duration = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+	if !found {
+		err = fmt.Errorf("unable to find video stream")
+	}
+	return
+}
+
+// InterpolateAt value at t from time sorted all
+func InterpolateAt(t time.Time, all []Timely) (c Timely, err error) {
+	index := FindIndex(t, all)
+	if index == -1 {
+		err = fmt.Errorf("%s not in range", t)
+		return
+	}
+	return Interpolate(t, all[index], all[index+1])
+}
@@ -0,0 +1,334 @@
+package gokart
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Camera identification read from MP4 user data
+type Camera struct {
+	Model    string `json:"model,omitempty"`
+	Serial   string `json:"serial,omitempty"`
+	Firmware string `json:"firmware,omitempty"`
+}
+
+// Metadata who and what was recorded, from a sidecar file next to the video
+// and from camera
+type Metadata struct {
+	Driver  string `json:"driver,omitempty"`
+	Kart    string `json:"kart,omitempty"`
+	Chassis string `json:"chassis,omitempty"`
+	Engine  string `json:"engine,omitempty"`
+	Tyres   string `json:"tyres,omitempty"`
+	// Temperature ambient in °C, nil when unknown
+	Temperature *float64 `json:"temperature,omitempty"`
+	Notes       string   `json:"notes,omitempty"`
+	Camera      Camera   `json:"camera"`
+	// CSV format of a GPS log set with csv sidecar keys, nil for DefaultCSVFormat
+	CSV *CSVFormat `json:"-"`
+}
+
+// IsZero nothing is known
+func (m Metadata) IsZero() bool {
+	return m.Driver == "" && m.Kart == "" && m.Chassis == "" && m.Engine == "" &&
+		m.Tyres == "" && m.Temperature == nil && m.Notes == "" && m.Camera == Camera{} && m.CSV == nil
+}
+
+// Description one line summary of kart, setup, conditions and camera
+func (m Metadata) Description() string {
+	var parts []string
+	for _, p := range []struct{ label, value string }{
+		{"kart", m.Kart},
+		{"chassis", m.Chassis},
+		{"engine", m.Engine},
+		{"tyres", m.Tyres},
+	} {
+		if p.value != "" {
+			parts = append(parts, p.label+" "+p.value)
+		}
+	}
+	if m.Temperature != nil {
+		parts = append(parts, strconv.FormatFloat(*m.Temperature, 'f', -1, 64)+"°C")
+	}
+	if m.Camera.Model != "" {
+		parts = append(parts, m.Camera.Model)
+	}
+	if m.Notes != "" {
+		parts = append(parts, m.Notes)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SidecarNames possible sidecar files of a video, first existing one is used:
+// same name with .json, .yaml or .yml extension
+func SidecarNames(video string) (names []string) {
+	base := strings.TrimSuffix(video, filepath.Ext(video))
+	for _, ext := range []string{".json", ".yaml", ".yml"} {
+		names = append(names, base+ext)
+	}
+	return
+}
+
+// ReadSidecar metadata of video from its sidecar file, zero when there is none
+func ReadSidecar(video string) (m Metadata, err error) {
+	for _, name := range SidecarNames(video) {
+		data, rerr := os.ReadFile(name)
+		if errors.Is(rerr, os.ErrNotExist) {
+			continue
+		}
+		if rerr != nil {
+			return m, rerr
+		}
+		if filepath.Ext(name) == ".json" {
+			var csv struct {
+				CSV map[string]string `json:"csv"`
+			}
+			if err = json.Unmarshal(data, &m); err == nil {
+				err = json.Unmarshal(data, &csv)
+			}
+			for key, value := range csv.CSV {
+				if err == nil {
+					err = m.setCSV(key, value)
+				}
+			}
+		} else {
+			err = parseYAML(data, &m)
+		}
+		if err != nil {
+			err = fmt.Errorf("unable to read %s:%s", name, err)
+		}
+		return
+	}
+	return
+}
+
+// setCSV field of GPS log format, starting from DefaultCSVFormat
+func (m *Metadata) setCSV(key, value string) error {
+	if m.CSV == nil {
+		f := DefaultCSVFormat
+		m.CSV = &f
+	}
+	return m.CSV.Set(key, value)
+}
+
+// parseYAML flat "key: value" files, enough for sidecars without a yaml dependency
+func parseYAML(data []byte, m *Metadata) (err error) {
+	fields := map[string]*string{
+		"driver":  &m.Driver,
+		"kart":    &m.Kart,
+		"chassis": &m.Chassis,
+		"engine":  &m.Engine,
+		"tyres":   &m.Tyres,
+		"notes":   &m.Notes,
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("line %d: missing ':'", n)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if unquoted, uerr := strconv.Unquote(value); uerr == nil {
+			value = unquoted
+		} else if len(value) > 1 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+		if key == "temperature" {
+			t, perr := strconv.ParseFloat(value, 64)
+			if perr != nil {
+				return fmt.Errorf("line %d: wrong temperature %q", n, value)
+			}
+			m.Temperature = &t
+			continue
+		}
+		if csvKey, ok := strings.CutPrefix(key, "csv_"); ok {
+			if err = m.setCSV(csvKey, value); err != nil {
+				return fmt.Errorf("line %d: %s", n, err)
+			}
+			continue
+		}
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("line %d: unknown key %q", n, key)
+		}
+		*field = value
+	}
+	return scanner.Err()
+}
+
+// mp4Box header of an MP4 box (atom)
+type mp4Box struct {
+	kind string
+	// size of content, -1 until end of file
+	size int64
+}
+
+// readBox header at current position
+func readBox(r io.Reader) (b mp4Box, err error) {
+	var header [8]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	size := int64(binary.BigEndian.Uint32(header[:4]))
+	b.kind = string(header[4:])
+	switch size {
+	case 0:
+		b.size = -1
+	case 1:
+		var large [8]byte
+		if _, err = io.ReadFull(r, large[:]); err != nil {
+			return
+		}
+		b.size = int64(binary.BigEndian.Uint64(large[:])) - 16
+	default:
+		b.size = size - 8
+	}
+	if b.size < -1 {
+		err = fmt.Errorf("wrong size for box %q", b.kind)
+	}
+	return
+}
+
+// findBox content of first box with path like moov/udta, at most limit bytes
+func findBox(r io.ReadSeeker, path []string, end int64, limit int64) (data []byte, err error) {
+	for {
+		pos, serr := r.Seek(0, io.SeekCurrent)
+		if serr != nil {
+			return nil, serr
+		}
+		if end >= 0 && pos >= end {
+			return nil, os.ErrNotExist
+		}
+		b, berr := readBox(r)
+		if berr != nil {
+			if berr == io.EOF {
+				return nil, os.ErrNotExist
+			}
+			return nil, berr
+		}
+		start, _ := r.Seek(0, io.SeekCurrent)
+		boxEnd := start + b.size
+		if b.size < 0 {
+			boxEnd = -1
+		}
+		if b.kind == path[0] {
+			if len(path) == 1 {
+				if b.size < 0 || b.size > limit {
+					return nil, fmt.Errorf("box %s is too large", b.kind)
+				}
+				data = make([]byte, b.size)
+				_, err = io.ReadFull(r, data)
+				return
+			}
+			return findBox(r, path[1:], boxEnd, limit)
+		}
+		if b.size < 0 {
+			return nil, os.ErrNotExist
+		}
+		if _, err = r.Seek(boxEnd, io.SeekStart); err != nil {
+			return
+		}
+	}
+}
+
+// gpmfStrings string values (type 'c') of GoPro GPMF key-length-value data, nested levels included
+func gpmfStrings(data []byte, values map[string]string) {
+	// truncated data still gives its first items
+	items, _ := gpmfItems(data)
+	for _, it := range items {
+		switch it.kind {
+		case 0:
+			gpmfStrings(it.value, values)
+		case 'c':
+			if _, ok := values[it.key]; !ok {
+				values[it.key] = strings.TrimRight(string(it.value), "\x00 ")
+			}
+		}
+	}
+}
+
+// ReadCamera camera model, serial and firmware from MP4 moov/udta box of a GoPro video,
+// FIRM and CAME boxes or MINF, DVNM, CASN and FMWR of its GPMF box
+func ReadCamera(filename string) (c Camera, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	udta, err := findBox(f, []string{"moov", "udta"}, -1, 16<<20)
+	if err != nil {
+		err = fmt.Errorf("unable to find user data in %s:%s", filename, err)
+		return
+	}
+	for len(udta) >= 8 {
+		size := int(binary.BigEndian.Uint32(udta[:4]))
+		if size < 8 || size > len(udta) {
+			break
+		}
+		kind, content := string(udta[4:8]), udta[8:size]
+		switch kind {
+		case "FIRM":
+			c.Firmware = strings.TrimRight(string(content), "\x00 ")
+		case "CAME":
+			// camera unique id when serial is not given
+			if c.Serial == "" {
+				c.Serial = hex.EncodeToString(content)
+			}
+		case "GPMF":
+			values := make(map[string]string)
+			gpmfStrings(content, values)
+			if v := values["MINF"]; v != "" {
+				c.Model = v
+			} else if v := values["DVNM"]; v != "" && v != "Camera" {
+				// device name, "Camera" on some firmwares
+				c.Model = v
+			}
+			if v := values["CASN"]; v != "" {
+				c.Serial = v
+			}
+			if v := values["FMWR"]; v != "" && c.Firmware == "" {
+				c.Firmware = v
+			}
+		}
+		udta = udta[size:]
+	}
+	return
+}
+
+// ReadMetadata sidecar and camera metadata of video, missing sidecar or camera
+// information are not errors
+func ReadMetadata(video string) (m Metadata, err error) {
+	if m, err = ReadSidecar(video); err != nil {
+		return
+	}
+	// sidecar values are kept when camera does not give them
+	c, cerr := ReadCamera(video)
+	if cerr != nil {
+		return
+	}
+	for _, f := range []struct{ dst, src *string }{
+		{&m.Camera.Model, &c.Model},
+		{&m.Camera.Serial, &c.Serial},
+		{&m.Camera.Firmware, &c.Firmware},
+	} {
+		if *f.src != "" {
+			*f.dst = *f.src
+		}
+	}
+	return
+}
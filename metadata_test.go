@@ -0,0 +1,101 @@
+package gokart
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// box MP4 box of kind with content
+func box(kind string, content ...[]byte) []byte {
+	data := bytes.Join(content, nil)
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(8+len(data)))
+	copy(header[4:], kind)
+	return append(header, data...)
+}
+
+// klv GPMF string or nested value
+func klv(key string, kind byte, value []byte) []byte {
+	if kind == 0 {
+		return klvs(key, kind, 4, value)
+	}
+	return klvs(key, kind, len(value), value)
+}
+
+// klvs GPMF value made of samples of size bytes
+func klvs(key string, kind byte, size int, value []byte) []byte {
+	header := []byte{key[0], key[1], key[2], key[3], kind, byte(size), 0, 0}
+	binary.BigEndian.PutUint16(header[6:], uint16(len(value)/size))
+	padded := make([]byte, (len(value)+3)&^3)
+	copy(padded, value)
+	return append(header, padded...)
+}
+
+func TestReadMetadata(t *testing.T) {
+	dir := t.TempDir()
+	video := filepath.Join(dir, "GX010001.MP4")
+	gpmf := klv("DEVC", 0, append(klv("MINF", 'c', []byte("HERO9 Black")), klv("CASN", 'c', []byte("C3441324500000"))...))
+	mp4 := append(box("ftyp", []byte("mp41")), box("mdat", make([]byte, 100))...)
+	mp4 = append(mp4, box("moov", box("mvhd", make([]byte, 20)), box("udta", box("FIRM", []byte("HD9.01.01.60.00")), box("GPMF", gpmf)))...)
+	if err := os.WriteFile(video, mp4, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ReadCamera(video)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != (Camera{"HERO9 Black", "C3441324500000", "HD9.01.01.60.00"}) {
+		t.Errorf("wrong camera %+v", c)
+	}
+
+	m, err := ReadMetadata(video)
+	if err != nil || m.Driver != "" || m.Camera.Model != "HERO9 Black" {
+		t.Errorf("without sidecar got %+v %v", m, err)
+	}
+
+	yaml := "# kart day\ndriver: Alice\nkart: '12'\nchassis: \"OTK: Tony\"\ntemperature: 18.5\n"
+	if err = os.WriteFile(filepath.Join(dir, "GX010001.yaml"), []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = ReadMetadata(video); err != nil {
+		t.Fatal(err)
+	}
+	if m.Driver != "Alice" || m.Kart != "12" || m.Chassis != "OTK: Tony" || m.Temperature == nil || *m.Temperature != 18.5 {
+		t.Errorf("wrong yaml metadata %+v", m)
+	}
+	if m.Camera.Model != "HERO9 Black" {
+		t.Errorf("camera not merged %+v", m.Camera)
+	}
+	if d := m.Description(); d != "kart 12, chassis OTK: Tony, 18.5°C, HERO9 Black" {
+		t.Errorf("wrong description %q", d)
+	}
+
+	// json is preferred to yaml
+	json := `{"driver": "Bob", "camera": {"model": "custom"}, "csv": {"comma": ";", "time": "ts"}}`
+	if err = os.WriteFile(filepath.Join(dir, "GX010001.json"), []byte(json), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = ReadMetadata(video); err != nil {
+		t.Fatal(err)
+	}
+	if m.Driver != "Bob" || m.Camera.Model != "HERO9 Black" || m.Camera.Firmware != "HD9.01.01.60.00" {
+		t.Errorf("wrong json metadata %+v", m)
+	}
+	if m.CSV == nil || m.CSV.Comma != ';' || m.CSV.Time[0] != "ts" || m.CSV.Latitude[0] != "latitude" {
+		t.Errorf("wrong json CSV format %+v", m.CSV)
+	}
+
+	if err = parseYAML([]byte("driver Alice"), &m); err == nil {
+		t.Errorf("missing ':' not detected")
+	}
+	if err = parseYAML([]byte("wheels: 4"), &m); err == nil {
+		t.Errorf("unknown key not detected")
+	}
+	if err = parseYAML([]byte("csv_wheels: 4"), &m); err == nil {
+		t.Errorf("unknown csv key not detected")
+	}
+}
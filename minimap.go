@@ -0,0 +1,182 @@
+package gokart
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"time"
+)
+
+// Marker a position to show on a MiniMap
+type Marker struct {
+	Position GPS5
+	Color    color.RGBA
+	// Ghost markers are smaller and empty, used for other drivers
+	Ghost bool
+}
+
+// MiniMap small anti-aliased track outline from a reference lap with moving markers,
+// outline is drawn once and reused for every image
+type MiniMap struct {
+	Track   *Track
+	Outline []GPS5
+	// Size width and height of the square mini-map in pixels
+	Size int
+	// LineWidth outline width in pixels
+	LineWidth    float64
+	Background   color.RGBA
+	OutlineColor color.RGBA
+	StartColor   color.RGBA
+	SectorColor  color.RGBA
+	// projection
+	limits Line
+	cos    float64
+	ratio  float64
+	// cache
+	background *image.RGBA
+}
+
+// NewMiniMap from track and reference lap samples gps[from:to+1]
+func NewMiniMap(track *Track, gps []Timely, from, to int, size int) (m *MiniMap, err error) {
+	if from < 0 || to >= len(gps) || to-from < 2 {
+		err = fmt.Errorf("not enough points for mini-map between %d and %d", from, to)
+		return
+	}
+	m = &MiniMap{
+		Track:        track,
+		Outline:      make([]GPS5, 0, to-from+1),
+		Size:         size,
+		LineWidth:    math.Max(1.5, float64(size)/120),
+		Background:   color.RGBA{0, 0, 0, 160},
+		OutlineColor: white,
+		StartColor:   red,
+		SectorColor:  orange,
+	}
+	for i := from; i <= to; i++ {
+		m.Outline = append(m.Outline, gps[i].Value.(GPS5))
+	}
+	return
+}
+
+// LapMiniMap mini-map using given lap as reference
+func (l LapCounter) LapMiniMap(gps []Timely, lap int, size int) (m *MiniMap, err error) {
+	from, to, err := l.LapRange(gps, lap)
+	if err != nil {
+		return
+	}
+	return NewMiniMap(l.track, gps, from, to, size)
+}
+
+// project compute limits and ratio so outline fit in mini-map with padding
+func (m *MiniMap) project() {
+	m.limits = NewLine(90, 180, -90, -180)
+	for _, g := range m.Outline {
+		m.limits.P1.Latitude = math.Min(m.limits.P1.Latitude, g.Latitude)
+		m.limits.P1.Longitude = math.Min(m.limits.P1.Longitude, g.Longitude)
+		m.limits.P2.Latitude = math.Max(m.limits.P2.Latitude, g.Latitude)
+		m.limits.P2.Longitude = math.Max(m.limits.P2.Longitude, g.Longitude)
+	}
+	// longitude degrees are shorter than latitude ones
+	m.cos = math.Cos((m.limits.P1.Latitude + m.limits.P2.Latitude) / 2 * math.Pi / 180)
+	w := (m.limits.P2.Longitude - m.limits.P1.Longitude) * m.cos
+	h := m.limits.P2.Latitude - m.limits.P1.Latitude
+	inner := float64(m.Size) * 0.8
+	m.ratio = math.Min(inner/w, inner/h)
+}
+
+// toXY position in mini-map
+func (m *MiniMap) toXY(g GPS5) (x, y float64) {
+	w := (m.limits.P2.Longitude - m.limits.P1.Longitude) * m.cos * m.ratio
+	h := (m.limits.P2.Latitude - m.limits.P1.Latitude) * m.ratio
+	// centered
+	size := float64(m.Size)
+	x = (size-w)/2 + (g.Longitude-m.limits.P1.Longitude)*m.cos*m.ratio
+	y = size - (size-h)/2 - (g.Latitude-m.limits.P1.Latitude)*m.ratio
+	return
+}
+
+// drawTick short stroke across the track at line position
+func (m *MiniMap) drawTick(img *image.RGBA, l Line, c color.RGBA) {
+	x1, y1 := m.toXY(l.P1)
+	x2, y2 := m.toXY(l.P2)
+	dx, dy := x2-x1, y2-y1
+	norm := math.Hypot(dx, dy)
+	if norm == 0 {
+		return
+	}
+	half := float64(m.Size) * 0.03
+	cx, cy := (x1+x2)/2, (y1+y2)/2
+	dx, dy = dx/norm*half, dy/norm*half
+	DrawPolyline(img, []Vertex{{cx - dx, cy - dy}, {cx + dx, cy + dy}},
+		Stroke{Width: m.LineWidth, Color: c})
+}
+
+// prepare draw background once
+func (m *MiniMap) prepare() {
+	if m.background != nil && m.background.Bounds().Dx() == m.Size {
+		return
+	}
+	m.project()
+	m.background = image.NewRGBA(image.Rect(0, 0, m.Size, m.Size))
+	DrawRectangle(m.background, 0, 0, m.Size, m.Size, m.Background)
+	outline := make([]Vertex, len(m.Outline))
+	for i, g := range m.Outline {
+		outline[i].X, outline[i].Y = m.toXY(g)
+	}
+	DrawPolyline(m.background, outline, Stroke{Width: m.LineWidth, Color: m.OutlineColor})
+	if m.Track != nil {
+		for _, sector := range m.Track.Sectors {
+			m.drawTick(m.background, sector, m.SectorColor)
+		}
+		m.drawTick(m.background, m.Track.Start, m.StartColor)
+	}
+}
+
+// drawMarker anti-aliased marker centered on its position
+func (m *MiniMap) drawMarker(dst *image.RGBA, at image.Point, marker Marker) {
+	x, y := m.toXY(marker.Position)
+	center := Vertex{float64(at.X) + x, float64(at.Y) + y}
+	radius := math.Max(3, float64(m.Size)/30)
+	if marker.Ghost {
+		radius *= 0.75
+		StrokeEllipse(dst, center, radius*5/6, radius*5/6, radius/3, marker.Color)
+		return
+	}
+	FillEllipse(dst, center, radius, radius, white)
+	FillEllipse(dst, center, radius*3/4, radius*3/4, marker.Color)
+}
+
+// Draw mini-map on dst with its top left corner at given point
+func (m *MiniMap) Draw(dst *image.RGBA, at image.Point, markers ...Marker) {
+	m.prepare()
+	draw.Draw(dst, m.background.Bounds().Add(at), m.background, image.Point{}, draw.Over)
+	// ghosts first, main markers must be visible
+	for _, ghost := range []bool{true, false} {
+		for _, marker := range markers {
+			if marker.Ghost != ghost {
+				continue
+			}
+			m.drawMarker(dst, at, marker)
+		}
+	}
+}
+
+// Image new mini-map image with markers
+func (m *MiniMap) Image(markers ...Marker) (img *image.RGBA) {
+	img = image.NewRGBA(image.Rect(0, 0, m.Size, m.Size))
+	m.Draw(img, image.Point{}, markers...)
+	return
+}
+
+// MarkerAt marker for position at time t in gps, false if t is out of range
+func MarkerAt(gps []Timely, t time.Time, c color.RGBA) (marker Marker, ok bool) {
+	inter, err := InterpolateAt(t, gps)
+	if err != nil {
+		return
+	}
+	marker = Marker{Position: inter.Value.(GPS5), Color: c}
+	ok = true
+	return
+}
@@ -0,0 +1,44 @@
+package gokart
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestMiniMap(t *testing.T) {
+	track, gps, laps := circleLaps([]float64{10, 12})
+	m, err := laps.LapMiniMap(gps, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	east := track.Start.P1
+	east.Latitude = (track.Start.P1.Latitude + track.Start.P2.Latitude) / 2
+	east.Longitude = (track.Start.P1.Longitude + track.Start.P2.Longitude) / 2
+	img := m.Image(Marker{Position: east, Color: blue})
+	// circle fills 80% of the square mini-map, start line on east side
+	x, y := m.toXY(east)
+	if math.Abs(x-90) > 0.5 || math.Abs(y-50) > 0.5 {
+		t.Errorf("east of circle is at %f,%f", x, y)
+	}
+	if c := img.RGBAAt(90, 50); c.B < 200 || c.R > 60 {
+		t.Errorf("no marker at east of circle %v", c)
+	}
+	if c := img.RGBAAt(50, 50); c != m.Background {
+		t.Errorf("center should be background %v", c)
+	}
+	// markers are drawn at offset with the mini-map
+	dst := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	m.Draw(dst, image.Pt(100, 20), Marker{Position: east, Color: blue})
+	if dst.RGBAAt(190, 70) != img.RGBAAt(90, 50) || dst.RGBAAt(90, 50) != (color.RGBA{}) {
+		t.Error("mini-map not drawn at offset")
+	}
+	if _, ok := MarkerAt(gps, gps[0].Time.Add(-1), red); ok {
+		t.Error("marker before first sample")
+	}
+	marker, ok := MarkerAt(gps, gps[10].Time, red)
+	if !ok || marker.Position != gps[10].Value.(GPS5) {
+		t.Errorf("wrong marker %+v", marker)
+	}
+}
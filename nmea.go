@@ -0,0 +1,272 @@
+package gokart
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KNOT in m/s
+const KNOT = 1852.0 / 3600
+
+// NMEASentence fields of a checked NMEA 0183 sentence like
+// $GPRMC,...*hh, Type is without talker id like "RMC"
+type NMEASentence struct {
+	Talker string
+	Type   string
+	Fields []string
+}
+
+// ParseNMEA check and split one sentence, checksum is optional
+func ParseNMEA(line string) (s NMEASentence, err error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "$") {
+		return s, fmt.Errorf("not a NMEA sentence %q", line)
+	}
+	body := line[1:]
+	if i := strings.LastIndexByte(body, '*'); i >= 0 {
+		expected, perr := strconv.ParseUint(body[i+1:], 16, 8)
+		if perr != nil {
+			return s, fmt.Errorf("invalid checksum of %q", line)
+		}
+		var sum byte
+		for _, c := range []byte(body[:i]) {
+			sum ^= c
+		}
+		if sum != byte(expected) {
+			return s, fmt.Errorf("wrong checksum of %q", line)
+		}
+		body = body[:i]
+	}
+	fields := strings.Split(body, ",")
+	if len(fields[0]) < 3 {
+		return s, fmt.Errorf("invalid NMEA address %q", line)
+	}
+	address := fields[0]
+	s.Talker, s.Type = address[:len(address)-3], address[len(address)-3:]
+	s.Fields = fields[1:]
+	return
+}
+
+// field i or empty when sentence is too short
+func (s NMEASentence) field(i int) string {
+	if i < len(s.Fields) {
+		return s.Fields[i]
+	}
+	return ""
+}
+
+// float field i, 0 when empty
+func (s NMEASentence) float(i int) (v float64, err error) {
+	if f := s.field(i); f != "" {
+		if v, err = strconv.ParseFloat(f, 64); err != nil {
+			err = fmt.Errorf("%s field %d:%w", s.Type, i+1, err)
+		}
+	}
+	return
+}
+
+// coordinate ddmm.mmmm field i with hemisphere in field i+1
+func (s NMEASentence) coordinate(i int) (deg float64, err error) {
+	v, err := s.float(i)
+	if err != nil {
+		return
+	}
+	d := float64(int(v / 100))
+	deg = d + (v-d*100)/60
+	if h := s.field(i + 1); h == "S" || h == "W" {
+		deg = -deg
+	}
+	return
+}
+
+// timeOfDay hhmmss.ss field i as duration since midnight UTC
+func (s NMEASentence) timeOfDay(i int) (d time.Duration, err error) {
+	f := s.field(i)
+	if len(f) < 6 {
+		return 0, fmt.Errorf("%s has no time", s.Type)
+	}
+	h, herr := strconv.Atoi(f[0:2])
+	m, merr := strconv.Atoi(f[2:4])
+	sec, serr := strconv.ParseFloat(f[4:], 64)
+	if err = errors.Join(herr, merr, serr); err != nil {
+		return 0, fmt.Errorf("%s time %q:%w", s.Type, f, err)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec*float64(time.Second)+0.5), nil
+}
+
+// NMEAFix position of one epoch, merged from its RMC, GGA and VTG sentences
+type NMEAFix struct {
+	// Day UTC date from RMC, zero until one RMC is read
+	Day time.Time
+	// TimeOfDay since midnight UTC
+	TimeOfDay time.Duration
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+	// Speed over ground in m/s
+	Speed float64
+	HDOP  float64
+	// Quality GGA fix quality, 0 when invalid
+	Quality int
+	// Valid RMC status is active or GGA has a fix
+	Valid        bool
+	hasPosition  bool
+	hasRMC       bool
+	hasGGA       bool
+	hasAltitude  bool
+	hasHDOP      bool
+	hasTimeOfDay bool
+}
+
+// Time UTC time of fix
+func (f NMEAFix) Time() time.Time {
+	return f.Day.Add(f.TimeOfDay)
+}
+
+// Timely GPS5 sample of fix, accuracy is HDOP×100 like GoPro GPS
+func (f NMEAFix) Timely() Timely {
+	g := NewGPS5(f.Latitude, f.Longitude)
+	g.Altitude = f.Altitude
+	g.Speed, g.Speed3D = f.Speed, f.Speed
+	g.Accuracy = 9999
+	if f.hasHDOP {
+		g.Accuracy = uint16(min(f.HDOP*100, 9999))
+	}
+	if f.Quality > 0 {
+		g.Fix = 3
+		if !f.hasAltitude {
+			g.Fix = 2
+		}
+	} else if f.Valid {
+		g.Fix = 2
+	}
+	return Timely{Time: f.Time(), Value: g}
+}
+
+// NMEADecoder merge sentences of each epoch in fixes, a fix is complete when
+// both RMC and GGA of its time are read, or when next epoch begins
+type NMEADecoder struct {
+	day     time.Time
+	current NMEAFix
+}
+
+// Flush last fix not returned yet, at end of stream
+func (d *NMEADecoder) Flush() (fix NMEAFix, ok bool) {
+	if d.current.ready() && !(d.current.hasRMC && d.current.hasGGA) {
+		fix, ok = d.current, true
+	}
+	d.current = NMEAFix{}
+	return
+}
+
+// apply sentence s to fix f
+func (f *NMEAFix) apply(s NMEASentence) (err error) {
+	switch s.Type {
+	case "RMC":
+		if f.Speed, err = s.float(6); err != nil {
+			return
+		}
+		f.Speed *= KNOT
+		f.hasRMC = true
+		f.Valid = f.Valid || s.field(1) == "A"
+	case "GGA":
+		if q := s.field(5); q != "" {
+			if f.Quality, err = strconv.Atoi(q); err != nil {
+				return fmt.Errorf("GGA quality:%w", err)
+			}
+		}
+		if f.HDOP, err = s.float(7); err != nil {
+			return
+		}
+		f.hasHDOP = s.field(7) != ""
+		if f.Altitude, err = s.float(8); err != nil {
+			return
+		}
+		f.hasAltitude = s.field(8) != ""
+		f.hasGGA = true
+		f.Valid = f.Valid || f.Quality > 0
+		return f.position(s, 1)
+	case "VTG":
+		if s.field(6) != "" {
+			var kmh float64
+			if kmh, err = s.float(6); err == nil {
+				f.Speed = kmh / 3.6
+			}
+		}
+		return
+	}
+	return f.position(s, 2)
+}
+
+// position latitude and longitude fields starting at i
+func (f *NMEAFix) position(s NMEASentence, i int) (err error) {
+	if s.field(i) == "" || s.field(i+2) == "" {
+		return
+	}
+	if f.Latitude, err = s.coordinate(i); err != nil {
+		return
+	}
+	if f.Longitude, err = s.coordinate(i + 2); err != nil {
+		return
+	}
+	f.hasPosition = true
+	return
+}
+
+// ready fix can be used, it has a position and a date
+func (f NMEAFix) ready() bool {
+	return f.hasPosition && f.Valid && !f.Day.IsZero()
+}
+
+// Decode one line, returns previous or current fix when it is complete,
+// other sentences are ignored, lines which are not NMEA are errors
+func (d *NMEADecoder) Decode(line string) (fix NMEAFix, ok bool, err error) {
+	s, err := ParseNMEA(line)
+	if err != nil {
+		return
+	}
+	switch s.Type {
+	case "RMC", "GGA":
+	case "VTG":
+		// no time, belongs to current epoch
+		err = d.current.apply(s)
+		return
+	default:
+		return
+	}
+	tod, err := s.timeOfDay(0)
+	if err != nil {
+		return
+	}
+	if s.Type == "RMC" {
+		if date := s.field(8); len(date) == 6 {
+			day, derr := time.Parse("020106", date)
+			if derr != nil {
+				return fix, false, fmt.Errorf("RMC date %q:%w", date, derr)
+			}
+			d.day = day
+		}
+	}
+	if d.current.hasTimeOfDay && tod != d.current.TimeOfDay {
+		// new epoch, previous one is complete when not already returned
+		if d.current.ready() && !(d.current.hasRMC && d.current.hasGGA) {
+			fix, ok = d.current, true
+		}
+		d.current = NMEAFix{}
+	}
+	d.current.TimeOfDay, d.current.hasTimeOfDay = tod, true
+	if d.current.Day.IsZero() || s.Type == "RMC" {
+		// date of RMC wins, GGA of first epoch after midnight may come before it
+		d.current.Day = d.day
+	}
+	if err = d.current.apply(s); err != nil {
+		return
+	}
+	if !ok && d.current.hasRMC && d.current.hasGGA && d.current.ready() {
+		fix, ok = d.current, true
+	}
+	return
+}
@@ -0,0 +1,82 @@
+package gokart
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// nmeaLine sentence with its checksum
+func nmeaLine(body string) string {
+	var sum byte
+	for _, c := range []byte(body) {
+		sum ^= c
+	}
+	return fmt.Sprintf("$%s*%02X", body, sum)
+}
+
+func TestParseNMEA(t *testing.T) {
+	s, err := ParseNMEA("$GPGGA,092750.000,5321.6802,N,00630.3372,W,1,8,1.03,61.7,M,55.2,M,,*76")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Talker != "GP" || s.Type != "GGA" || len(s.Fields) != 14 {
+		t.Errorf("wrong sentence %+v", s)
+	}
+	if lat, _ := s.coordinate(1); math.Abs(lat-53.36134) > 1e-5 {
+		t.Errorf("latitude is %f should be 53.36134", lat)
+	}
+	if lon, _ := s.coordinate(3); math.Abs(lon+6.50562) > 1e-5 {
+		t.Errorf("longitude is %f should be -6.50562", lon)
+	}
+	for _, line := range []string{
+		"$GPGGA,092750.000,5321.6802,N,00630.3372,W,1,8,1.03,61.7,M,55.2,M,,*77",
+		"GPGGA,092750.000",
+		"$G*00",
+	} {
+		if _, err = ParseNMEA(line); err == nil {
+			t.Errorf("%q should fail", line)
+		}
+	}
+}
+
+func TestNMEADecoder(t *testing.T) {
+	var d NMEADecoder
+	var fixes []NMEAFix
+	for _, body := range []string{
+		// GGA before RMC of first epoch
+		"GPGGA,235959.90,4700.0000,N,00012.0000,E,1,9,0.9,50.0,M,,M,,",
+		"GPRMC,235959.90,A,4700.0000,N,00012.0000,E,19.44,90.0,311224,,,A",
+		"GPVTG,90.0,T,,M,19.44,N,36.0,K,A",
+		// only RMC for next epochs, returned when next one begins
+		"GPRMC,000000.00,A,4700.0000,N,00012.0100,E,19.44,90.0,010125,,,A",
+		"GPGSV,3,1,11,03,03,111,00,04,15,270,00,06,01,010,00,13,06,292,00",
+		"GPRMC,000000.10,V,4700.0000,N,00012.0200,E,0,90.0,010125,,,N",
+	} {
+		fix, ok, err := d.Decode(nmeaLine(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			fixes = append(fixes, fix)
+		}
+	}
+	if _, ok := d.Flush(); ok {
+		t.Error("fix without valid status should not be returned")
+	}
+	if len(fixes) != 2 {
+		t.Fatalf("found %d fixes should be 2", len(fixes))
+	}
+	g := fixes[0].Timely()
+	if !g.Time.Equal(time.Date(2024, 12, 31, 23, 59, 59, 900000000, time.UTC)) {
+		t.Errorf("first fix at %s", g.Time)
+	}
+	gps := g.Value.(GPS5)
+	if gps.Altitude != 50 || gps.Accuracy != 90 || gps.Fix != 3 || math.Abs(gps.Speed-10) > 0.01 {
+		t.Errorf("wrong first fix %+v", gps)
+	}
+	if next := fixes[1].Timely(); !next.Time.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) || next.Value.(GPS5).Fix != 2 {
+		t.Errorf("wrong fix after midnight %+v", next)
+	}
+}
@@ -0,0 +1,387 @@
+package gokart
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// OverlayOptions widgets and encoding used by Overlay
+type OverlayOptions struct {
+	// Path where logos are stored, LogoFile and LogoMask are used as is when empty
+	Path string
+	// Start and Duration part of the video to render, zero Duration for whole video
+	Start    time.Duration
+	Duration time.Duration
+	// Widgets
+	Speed   bool
+	Chrono  bool
+	Delta   bool
+	Sectors bool
+	MiniMap bool
+	Logo    bool
+	// Codec for output video and its constant rate factor
+	Codec string
+	CRF   int
+}
+
+// DefaultOverlayOptions all widgets, h264 output
+var DefaultOverlayOptions = OverlayOptions{
+	Speed:   true,
+	Chrono:  true,
+	Delta:   true,
+	Sectors: true,
+	MiniMap: true,
+	Logo:    true,
+	Codec:   "libx264",
+	CRF:     20,
+}
+
+var (
+	panelColor = color.RGBA{0, 0, 0, 160}
+	aheadColor = green
+	lateColor  = red
+)
+
+// lapTrace elapsed time along lap distance, used for delta
+type lapTrace struct {
+	distance []float64
+	elapsed  []time.Duration
+}
+
+// newLapTrace from GPS samples between from and to, lap started at start
+func newLapTrace(gps []Timely, start time.Time, from, to int) (lt lapTrace) {
+	lt.distance = make([]float64, 0, to-from+1)
+	lt.elapsed = make([]time.Duration, 0, to-from+1)
+	d := 0.0
+	for i := from; i <= to; i++ {
+		if i > from {
+			d += Distance(gps[i-1].Value.(GPS5), gps[i].Value.(GPS5))
+		}
+		lt.distance = append(lt.distance, d)
+		lt.elapsed = append(lt.elapsed, gps[i].Time.Sub(start))
+	}
+	return
+}
+
+// ElapsedAt time needed to reach distance d, false if out of trace
+func (lt lapTrace) ElapsedAt(d float64) (elapsed time.Duration, ok bool) {
+	if len(lt.distance) < 2 || d < 0 || d > lt.distance[len(lt.distance)-1] {
+		return
+	}
+	i := sort.SearchFloat64s(lt.distance, d)
+	if i == 0 {
+		return lt.elapsed[0], true
+	}
+	ratio := (d - lt.distance[i-1]) / (lt.distance[i] - lt.distance[i-1])
+	elapsed = lt.elapsed[i-1] + time.Duration(ratio*float64(lt.elapsed[i]-lt.elapsed[i-1]))
+	ok = true
+	return
+}
+
+// Overlay draw telemetry widgets over GoPro video frames
+type Overlay struct {
+	filename string
+	opts     OverlayOptions
+	info     VideoInfo
+	gps      []Timely
+	track    *Track
+	// reference lap for delta and mini-map
+	best     lapTrace
+	minimap  *MiniMap
+	logo     image.Image
+	logoMask image.Image
+	// logo resized for video
+	scaledLogo *image.RGBA
+	// live state, frames must be drawn in time order
+	counter  LapCounter
+	index    int
+	lap      int
+	distance float64
+}
+
+// NewOverlay read telemetry and find track and best lap of given GoPro video
+func NewOverlay(filename string, opts OverlayOptions) (o *Overlay, err error) {
+	s, err := LoadSession(filename)
+	if err != nil {
+		return
+	}
+	return NewSessionOverlay(s, opts)
+}
+
+// NewSessionOverlay overlay for an already loaded session
+func NewSessionOverlay(s *Session, opts OverlayOptions) (o *Overlay, err error) {
+	o = &Overlay{filename: s.Filename, opts: opts, gps: s.GPS, track: s.Track}
+	if o.info, err = GetVideoInfo(s.Filename); err != nil {
+		return
+	}
+	from, to, lerr := s.Laps.LapRange(o.gps, s.Laps.Best())
+	if lerr == nil {
+		o.best = newLapTrace(o.gps, s.Laps.LapStart(s.Laps.Best()), from, to)
+	} else {
+		// no full lap, show everything
+		from, to = 0, len(o.gps)-1
+	}
+	// size is updated with first frame
+	if o.minimap, err = NewMiniMap(o.track, o.gps, from, to, 320); err != nil {
+		return
+	}
+	if opts.Logo && o.track.LogoFile != "" {
+		if o.logo, o.logoMask, err = o.track.LoadLogo(opts.Path); err != nil {
+			return
+		}
+	}
+	o.counter = NewLapCounter(o.track)
+	o.counter.SetOutput(nil)
+	return
+}
+
+// LoadLogo track logo and optional mask, from path when given
+func (t Track) LoadLogo(path string) (logo, mask image.Image, err error) {
+	load := func(name string) (img image.Image, err error) {
+		if path != "" {
+			name = filepath.Join(path, filepath.Base(name))
+		}
+		var f *os.File
+		if f, err = os.Open(name); err != nil {
+			return
+		}
+		defer f.Close()
+		img, _, err = image.Decode(f)
+		return
+	}
+	if logo, err = load(t.LogoFile); err != nil {
+		return
+	}
+	if t.LogoMask != "" {
+		mask, err = load(t.LogoMask)
+	}
+	return
+}
+
+// Track found for video
+func (o *Overlay) Track() *Track {
+	return o.track
+}
+
+// VideoInfo of overlayed video
+func (o *Overlay) VideoInfo() VideoInfo {
+	return o.info
+}
+
+// TimeAt telemetry time of frame n (counted from rendering start)
+func (o *Overlay) TimeAt(n int) time.Time {
+	// "best" video starting point time is first GPS time
+	return o.gps[0].Time.Add(o.opts.Start + o.info.FrameTime(n))
+}
+
+// advance live lap counter up to t
+func (o *Overlay) advance(t time.Time) {
+	for o.index+1 < len(o.gps) && !o.gps[o.index+1].Time.After(t) {
+		prev, current := o.gps[o.index], o.gps[o.index+1]
+		o.counter.Update(prev.Time, prev, current)
+		if o.counter.Current() != o.lap {
+			// new lap, distance from start line
+			o.lap = o.counter.Current()
+			o.distance = 0
+		}
+		o.distance += Distance(prev.Value.(GPS5), current.Value.(GPS5))
+		o.index++
+	}
+}
+
+// position interpolated at t and distance in current lap
+func (o *Overlay) position(t time.Time) (pos GPS5, distance float64, ok bool) {
+	if o.index+1 >= len(o.gps) || t.Before(o.gps[o.index].Time) {
+		return
+	}
+	inter, err := Interpolate(t, o.gps[o.index], o.gps[o.index+1])
+	if err != nil {
+		return
+	}
+	pos = inter.Value.(GPS5)
+	distance = o.distance + Distance(o.gps[o.index].Value.(GPS5), pos)
+	ok = true
+	return
+}
+
+// Draw widgets on img for time t, successive calls must have increasing t
+func (o *Overlay) Draw(img *image.RGBA, t time.Time) (err error) {
+	o.advance(t)
+	pos, distance, ok := o.position(t)
+	r := img.Bounds()
+	// everything is designed for 1080p
+	u := float64(r.Dy()) / 1080.
+	scale := func(v float64) int {
+		return int(v*u + 0.5)
+	}
+	margin := scale(32)
+	if o.opts.Logo && o.logo != nil {
+		o.drawLogo(img, image.Rect(r.Min.X+margin, r.Min.Y+margin, r.Min.X+margin+scale(240), r.Min.Y+margin+scale(240)))
+	}
+	if o.opts.MiniMap && ok {
+		o.minimap.Size = scale(320)
+		o.minimap.Draw(img, image.Pt(r.Max.X-margin-o.minimap.Size, r.Min.Y+margin), Marker{Position: pos, Color: red})
+	}
+	// text panel bottom left
+	lines := make([]string, 0)
+	colors := make([]color.RGBA, 0)
+	if o.opts.Chrono {
+		chrono := "--:--.--"
+		if o.counter.Current() > 0 {
+			chrono = DurationToChrono(o.counter.CurrentTime(t))
+		}
+		lines = append(lines, fmt.Sprintf("LAP %02d %s", o.counter.Current(), chrono))
+		colors = append(colors, white)
+	}
+	if o.opts.Delta {
+		delta, c := "Δ  --.--", white
+		if best, bok := o.best.ElapsedAt(distance); ok && bok && o.counter.Current() > 0 {
+			d := o.counter.CurrentTime(t) - best
+			sign := "+"
+			c = lateColor
+			if d < 0 {
+				sign = "-"
+				c = aheadColor
+				d = -d
+			}
+			delta = fmt.Sprintf("Δ %s%d.%02d", sign, d/time.Second, (d%time.Second)/(10*time.Millisecond))
+		}
+		lines = append(lines, delta)
+		colors = append(colors, c)
+	}
+	if o.opts.Speed && ok {
+		lines = append(lines, fmt.Sprintf("%3.0f km/h", pos.Speed3D*3.6))
+		colors = append(colors, white)
+	}
+	size := scale(56)
+	width, height := 0, 0
+	for _, line := range lines {
+		w, h := TextSize(line, size)
+		if w > width {
+			width = w
+		}
+		height += h
+	}
+	sectors := len(o.counter.SectorStatus())
+	barHeight := 0
+	if o.opts.Sectors && sectors > 1 {
+		barHeight = scale(24)
+		if width < scale(60)*sectors {
+			width = scale(60) * sectors
+		}
+	}
+	if len(lines) == 0 && barHeight == 0 {
+		return
+	}
+	x := r.Min.X + margin
+	y := r.Max.Y - margin - height - barHeight - 2*scale(16)
+	BlendRectangle(img, x, y, x+width+2*scale(16), r.Max.Y-margin, panelColor)
+	x += scale(16)
+	y += scale(16)
+	for i, line := range lines {
+		if err = DrawText(img, x, y, line, size, colors[i]); err != nil {
+			return
+		}
+		_, h := TextSize(line, size)
+		y += h
+	}
+	if barHeight > 0 {
+		w := width / sectors
+		for i, status := range o.counter.SectorStatus() {
+			DrawRectangle(img, x+i*w, y, x+(i+1)*w-scale(4), y+barHeight, SectorColor(status))
+		}
+	}
+	return
+}
+
+// drawLogo fit track logo in r, resized logo is kept for next frames
+func (o *Overlay) drawLogo(img *image.RGBA, r image.Rectangle) {
+	if o.scaledLogo == nil || o.scaledLogo.Bounds().Dx() > r.Dx() || o.scaledLogo.Bounds().Dy() > r.Dy() {
+		b := o.logo.Bounds()
+		ratio := math.Min(float64(r.Dx())/float64(b.Dx()), float64(r.Dy())/float64(b.Dy()))
+		w, h := int(float64(b.Dx())*ratio), int(float64(b.Dy())*ratio)
+		o.scaledLogo = image.NewRGBA(image.Rect(0, 0, w, h))
+		// nearest neighbour resize, logo is small
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				sx := b.Min.X + int(float64(x)/ratio)
+				sy := b.Min.Y + int(float64(y)/ratio)
+				if o.logoMask != nil {
+					if _, _, _, a := o.logoMask.At(sx, sy).RGBA(); a == 0 {
+						continue
+					}
+				}
+				o.scaledLogo.Set(x, y, o.logo.At(sx, sy))
+			}
+		}
+	}
+	draw.Draw(img, o.scaledLogo.Bounds().Add(r.Min), o.scaledLogo, image.Point{}, draw.Over)
+}
+
+// encoder ffmpeg process reading raw rgba frames, audio is copied from original
+func (o *Overlay) encoder(out string) *ffmpeg.Stream {
+	streams := []*ffmpeg.Stream{
+		ffmpeg.Input("pipe:", ffmpeg.KwArgs{
+			"format":    "rawvideo",
+			"pix_fmt":   "rgba",
+			"s":         fmt.Sprintf("%dx%d", o.info.Width, o.info.Height),
+			"framerate": fmt.Sprintf("%d/%d", o.info.RateNum, o.info.RateDen),
+		}),
+	}
+	kw := ffmpeg.KwArgs{"c:v": o.opts.Codec, "pix_fmt": "yuv420p"}
+	if o.opts.CRF > 0 {
+		kw["crf"] = o.opts.CRF
+	}
+	if o.info.HasAudio {
+		in := ffmpeg.KwArgs{}
+		if o.opts.Start > 0 {
+			in["ss"] = fmt.Sprintf("%.3f", o.opts.Start.Seconds())
+		}
+		streams = append(streams, ffmpeg.Input(o.filename, in).Audio())
+		kw["c:a"] = "copy"
+		kw["shortest"] = ""
+	}
+	return ffmpeg.Output(streams, out, kw).OverWriteOutput()
+}
+
+// Render decode video, draw widgets on each frame and encode result in out
+func (o *Overlay) Render(out string) (err error) {
+	er, ew := io.Pipe()
+	encoded := make(chan error, 1)
+	go func() {
+		eerr := o.encoder(out).WithInput(er).Run()
+		er.CloseWithError(eerr)
+		encoded <- eerr
+	}()
+	err = DecodeFrames(o.filename, o.info, o.opts.Start, o.opts.Duration, func(n int, img *image.RGBA) (err error) {
+		if err = o.Draw(img, o.TimeAt(n)); err != nil {
+			return
+		}
+		_, err = ew.Write(img.Pix)
+		return
+	})
+	ew.Close()
+	if eerr := <-encoded; err == nil && eerr != nil {
+		err = fmt.Errorf("encoding %s:%s", out, eerr)
+	}
+	return
+}
+
+// RenderOverlay write in GoPro video with telemetry widgets as out
+func RenderOverlay(in, out string, opts OverlayOptions) (err error) {
+	o, err := NewOverlay(in, opts)
+	if err != nil {
+		return
+	}
+	return o.Render(out)
+}
@@ -0,0 +1,117 @@
+package gokart
+
+import (
+	"image"
+	"math"
+	"testing"
+	"time"
+)
+
+// circleLaps laps around a 100m radius circle at 10Hz, one speed (m/s) per lap,
+// start line at east and two sectors
+func circleLaps(speeds []float64) (track *Track, gps []Timely, laps LapCounter) {
+	const radius = 100.0
+	center := NewGPS5(47.0, 0.2)
+	// meters to degrees
+	dlat := 1 / 111320.0
+	dlon := dlat / math.Cos(center.Latitude*math.Pi/180)
+	at := func(angle, r float64) GPS5 {
+		return NewGPS5(center.Latitude+r*math.Sin(angle)*dlat, center.Longitude+r*math.Cos(angle)*dlon)
+	}
+	radial := func(angle float64) Line {
+		return Line{P1: at(angle, radius-10), P2: at(angle, radius+10)}
+	}
+	track = &Track{
+		Name:    "Circle",
+		Start:   radial(0),
+		Sectors: []Line{radial(2 * math.Pi / 3), radial(4 * math.Pi / 3)},
+	}
+	t := time.Date(2024, 9, 14, 11, 12, 0, 0, time.UTC)
+	// start just before start line
+	angle := -0.1
+	for _, speed := range speeds {
+		for stop := angle + 2*math.Pi; angle < stop; angle += speed * 0.1 / radius {
+			g := at(angle, radius)
+			g.Speed3D = speed
+			gps = append(gps, Timely{Time: t, Value: g})
+			t = t.Add(100 * time.Millisecond)
+		}
+	}
+	laps = NewLapCounter(track)
+	laps.SetOutput(nil)
+	for i := 1; i < len(gps); i++ {
+		laps.Update(gps[i-1].Time, gps[i-1], gps[i])
+	}
+	return
+}
+
+func TestLapTrace(t *testing.T) {
+	_, gps, laps := circleLaps([]float64{10, 12, 11})
+	from, to, err := laps.LapRange(gps, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lt := newLapTrace(gps, laps.laps[1][0], from, to)
+	last := len(lt.distance) - 1
+	for _, c := range []struct {
+		d       float64
+		elapsed time.Duration
+		ok      bool
+	}{
+		{0, lt.elapsed[0], true},
+		{lt.distance[last], lt.elapsed[last], true},
+		// half way between two samples
+		{(lt.distance[5] + lt.distance[6]) / 2, (lt.elapsed[5] + lt.elapsed[6]) / 2, true},
+		{-1, 0, false},
+		{lt.distance[last] + 0.1, 0, false},
+	} {
+		elapsed, ok := lt.ElapsedAt(c.d)
+		if ok != c.ok || (elapsed-c.elapsed).Abs() > time.Microsecond {
+			t.Errorf("elapsed at %fm is %s %v should be %s %v", c.d, elapsed, ok, c.elapsed, c.ok)
+		}
+	}
+	if _, ok := (lapTrace{}).ElapsedAt(0); ok {
+		t.Error("empty trace has no elapsed time")
+	}
+}
+
+func TestOverlayDraw(t *testing.T) {
+	track, gps, laps := circleLaps([]float64{10, 12, 11})
+	from, to, err := laps.LapRange(gps, laps.Best())
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &Overlay{opts: DefaultOverlayOptions, gps: gps, track: track}
+	o.opts.Logo = false
+	o.best = newLapTrace(gps, laps.laps[laps.Best()][0], from, to)
+	if o.minimap, err = NewMiniMap(track, gps, from, to, 320); err != nil {
+		t.Fatal(err)
+	}
+	o.counter = NewLapCounter(track)
+	o.counter.SetOutput(nil)
+	img := image.NewRGBA(image.Rect(0, 0, 640, 360))
+	// middle of lap 2
+	at := laps.laps[2][0].Add(laps.laps[3][0].Sub(laps.laps[2][0]) / 2)
+	if err = o.Draw(img, at); err != nil {
+		t.Fatal(err)
+	}
+	if o.counter.Current() != 2 {
+		t.Errorf("overlay is in lap %d should be 2", o.counter.Current())
+	}
+	// mini-map in top right corner, scaled from 1080p
+	if o.minimap.Size != 107 {
+		t.Errorf("mini-map size is %d", o.minimap.Size)
+	}
+	pos, _, ok := o.position(at)
+	if !ok {
+		t.Fatal("no position")
+	}
+	x, y := o.minimap.toXY(pos)
+	if c := img.RGBAAt(640-11-107+int(x), 11+int(y)); c.R < 200 || c.G > 60 {
+		t.Errorf("no marker at %f,%f %v", x, y, c)
+	}
+	// panel in bottom left corner
+	if img.RGBAAt(15, 360-15).A == 0 || img.RGBAAt(320, 180).A != 0 {
+		t.Error("wrong text panel")
+	}
+}
@@ -0,0 +1,180 @@
+package gokart
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Orientation order of IMU axes as stored by camera, GPMF ORIN convention:
+// "ZXY" means first stored value is Z, lower case letter means axis is inverted
+type Orientation string
+
+// Apply reorder raw values to X, Y, Z, raw values are returned unchanged when
+// orientation is not valid
+func (o Orientation) Apply(raw [3]float64) (xyz [3]float64) {
+	if !o.valid() {
+		return raw
+	}
+	for i, c := range o {
+		sign := 1.0
+		if c >= 'a' {
+			sign = -1
+			c -= 'a' - 'A'
+		}
+		xyz[c-'X'] = sign * raw[i]
+	}
+	return
+}
+
+// valid three letters with each axis once
+func (o Orientation) valid() bool {
+	if len(o) != 3 {
+		return false
+	}
+	seen := 0
+	for _, c := range strings.ToUpper(string(o)) {
+		if c < 'X' || c > 'Z' {
+			return false
+		}
+		seen |= 1 << (c - 'X')
+	}
+	return seen == 7
+}
+
+// ACCL reorder axes of accelerometer sample
+func (o Orientation) ACCL(a ACCL) ACCL {
+	xyz := o.Apply([3]float64{a.X, a.Y, a.Z})
+	a.X, a.Y, a.Z = xyz[0], xyz[1], xyz[2]
+	return a
+}
+
+// GYRO reorder axes of gyroscope sample
+func (o Orientation) GYRO(g GYRO) GYRO {
+	xyz := o.Apply([3]float64{g.X, g.Y, g.Z})
+	g.X, g.Y, g.Z = xyz[0], xyz[1], xyz[2]
+	return g
+}
+
+// ReadOrientations ORIN of each stream of raw gpmd by sensor key like ACCL or
+// GYRO. HERO8 and later write ORIN, samples of older cameras are used as stored.
+func ReadOrientations(gpmd []byte) (orientations map[string]Orientation) {
+	orientations = make(map[string]Orientation)
+	// stream may be cut at end of video, complete devices are still read
+	devices, _ := gpmfItems(gpmd)
+	for _, device := range devices {
+		if device.key != "DEVC" || device.kind != 0 {
+			continue
+		}
+		streams, _ := gpmfItems(device.value)
+		for _, stream := range streams {
+			if stream.key != "STRM" || stream.kind != 0 {
+				continue
+			}
+			items, _ := gpmfItems(stream.value)
+			var orin Orientation
+			for _, it := range items {
+				switch it.key {
+				case "ORIN":
+					orin = Orientation(strings.TrimRight(string(it.value), "\x00"))
+				case "ACCL", "GYRO":
+					if _, ok := orientations[it.key]; !ok && orin != "" {
+						orientations[it.key] = orin
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// Quirks how telemetry of a camera generation must be read
+type Quirks struct {
+	// Generation HERO number, 0 when camera is unknown
+	Generation int `json:"generation"`
+	// LocalCreationTime creation_time of video is camera local time written as UTC
+	LocalCreationTime bool `json:"localcreationtime"`
+	// GPS stream read first, "GPS5" or "GPS9", the other one is read when
+	// it is empty, empty when camera has no GPS
+	GPS string `json:"gps"`
+	// IMU orientation of ACCL samples read from video, see ReadOrientations
+	IMU Orientation `json:"imu,omitempty"`
+}
+
+// DefaultQuirks unknown camera, GPS5 is read first
+var DefaultQuirks = Quirks{LocalCreationTime: true, GPS: "GPS5"}
+
+// QUIRKS per HERO generation, cameras not in table use DefaultQuirks.
+// Streams of each camera are listed in GPMF documentation,
+// https://github.com/gopro/gpmf-parser#where-to-find-gpmf-data
+var QUIRKS = map[int]Quirks{
+	8:  {Generation: 8, LocalCreationTime: true, GPS: "GPS5"},
+	9:  {Generation: 9, LocalCreationTime: true, GPS: "GPS5"},
+	10: {Generation: 10, LocalCreationTime: true, GPS: "GPS5"},
+	// HERO11 records GPS5 and GPS9, GPS5 has a higher rate
+	11: {Generation: 11, LocalCreationTime: true, GPS: "GPS5"},
+	// HERO12 has no GPS receiver, HERO12 and later have a time zone
+	// setting and write creation_time in UTC
+	12: {Generation: 12},
+	// HERO13 only records GPS9
+	13: {Generation: 13, GPS: "GPS9"},
+}
+
+// firmwarePrefixes first part of firmware version per generation, HERO10
+// and later use year of design
+var firmwarePrefixes = map[string]int{
+	"HD8": 8,
+	"HD9": 9,
+	"H21": 10,
+	"H22": 11,
+	"H23": 12,
+	"H24": 13,
+}
+
+var heroModel = regexp.MustCompile(`(?i)HERO\s*(\d+)`)
+
+// Generation HERO number from model name or firmware version, 0 when unknown
+func (c Camera) Generation() int {
+	if m := heroModel.FindStringSubmatch(c.Model); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	prefix, _, _ := strings.Cut(c.Firmware, ".")
+	return firmwarePrefixes[strings.ToUpper(prefix)]
+}
+
+// QuirksFor camera, DefaultQuirks when generation is unknown
+func QuirksFor(c Camera) (q Quirks) {
+	q, ok := QUIRKS[c.Generation()]
+	if !ok {
+		q = DefaultQuirks
+	}
+	return
+}
+
+// creationTime parse creation_time tag of video
+func (q Quirks) creationTime(s string) (start time.Time, err error) {
+	if start, err = time.Parse(time.RFC3339, s); err != nil {
+		return
+	}
+	if q.LocalCreationTime {
+		// TODO find location in Meta data
+		var location *time.Location
+		if location, err = time.LoadLocation("Europe/Paris"); err != nil {
+			return
+		}
+		// Hard change as string is wrong on my GoPro
+		start = time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), location)
+	}
+	return
+}
+
+// checkGPS error when GPS samples can not be expected from camera
+func (q Quirks) checkGPS() (err error) {
+	if q.GPS == "" {
+		err = fmt.Errorf("HERO%d has no GPS", q.Generation)
+	}
+	return
+}
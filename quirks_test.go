@@ -0,0 +1,141 @@
+package gokart
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cedricjoulain/gopro-utils/telemetry"
+)
+
+func TestQuirks(t *testing.T) {
+	for _, c := range []struct {
+		camera     Camera
+		generation int
+	}{
+		{Camera{Model: "HERO8 Black"}, 8},
+		{Camera{Model: "GoPro HERO11 Black Mini"}, 11},
+		{Camera{Firmware: "HD9.01.01.72.00"}, 9},
+		{Camera{Firmware: "H21.01.01.62.00"}, 10},
+		{Camera{Firmware: "H23.01.02.32.00"}, 12},
+		{Camera{Model: "MAX"}, 0},
+		{Camera{}, 0},
+	} {
+		if g := c.camera.Generation(); g != c.generation {
+			t.Errorf("%+v generation is %d should be %d", c.camera, g, c.generation)
+		}
+	}
+	if q := QuirksFor(Camera{Model: "HERO13 Black"}); q.GPS != "GPS9" {
+		t.Errorf("HERO13 should read GPS9 first %+v", q)
+	}
+	if q := QuirksFor(Camera{}); q != DefaultQuirks {
+		t.Errorf("unknown camera quirks %+v", q)
+	}
+	if err := QuirksFor(Camera{Model: "HERO12 Black"}).checkGPS(); err == nil {
+		t.Errorf("HERO12 has no GPS")
+	}
+	if err := QuirksFor(Camera{Model: "HERO9 Black"}).checkGPS(); err != nil {
+		t.Error(err)
+	}
+
+	raw := [3]float64{1, 2, 3}
+	for _, c := range []struct {
+		o   Orientation
+		xyz [3]float64
+	}{
+		{"XYZ", [3]float64{1, 2, 3}},
+		{"ZXY", [3]float64{2, 3, 1}},
+		{"YxZ", [3]float64{-2, 1, 3}},
+		{"XXZ", raw},
+		{"", raw},
+	} {
+		if xyz := c.o.Apply(raw); xyz != c.xyz {
+			t.Errorf("%q gives %v should be %v", c.o, xyz, c.xyz)
+		}
+	}
+}
+
+func TestCreationTime(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, c := range []struct {
+		camera Camera
+		start  time.Time
+	}{
+		// local time written as UTC
+		{Camera{Model: "HERO9 Black"}, time.Date(2024, 9, 14, 11, 12, 0, 0, paris)},
+		{Camera{Model: "HERO13 Black"}, time.Date(2024, 9, 14, 11, 12, 0, 0, time.UTC)},
+	} {
+		start, err := QuirksFor(c.camera).creationTime("2024-09-14T11:12:00.000000Z")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !start.Equal(c.start) {
+			t.Errorf("%s creation time is %s should be %s", c.camera.Model, start, c.start)
+		}
+	}
+}
+
+func TestReadSamples(t *testing.T) {
+	t0 := time.Date(2024, 9, 14, 11, 6, 40, 0, time.UTC)
+	telem := []*telemetry.TELEM{
+		{
+			Accl: []telemetry.ACCL{{X: 9.8, Y: 1, Z: 2}},
+			Gyro: []telemetry.GYRO{{X: 0.1, Y: 0.2, Z: 0.3}},
+			Gps:  []telemetry.GPS5{{Latitude: 47.3905, Longitude: -1.1605}},
+			Time: telemetry.TIME{Time: t0},
+		},
+	}
+	imu := func(orin string, key string) []byte {
+		return klv("STRM", 0, append(klv("ORIN", 'c', []byte(orin)), klvs(key, 's', 6, make([]byte, 6))...))
+	}
+	gpmd := klv("DEVC", 0, slices.Concat(
+		klv("DVNM", 'c', []byte("HERO13 Black")),
+		imu("ZXY", "ACCL"),
+		imu("YxZ", "GYRO"),
+		klv("STRM", 0, gps9Strm(gps9Bytes(47.3901, -1.1601, 40000000), gps9Bytes(47.3902, -1.1602, 40000100))),
+	))
+	for _, c := range []struct {
+		model string
+		// latitude of first GPS sample, 0 when GPS5 is not parsed
+		latitude float64
+	}{
+		{"HERO13 Black", 47.3901},
+		{"HERO11 Black", 47.3905},
+	} {
+		s := &Session{Telemetry: telem, Quirks: QuirksFor(Camera{Model: c.model})}
+		if err := s.readSamples(gpmd); err != nil {
+			t.Fatal(err)
+		}
+		if len(s.GPS) == 0 || math.Abs(s.GPS[0].Value.(GPS5).Latitude-c.latitude) > 1e-7 {
+			t.Errorf("%s read wrong GPS stream %v", c.model, s.GPS)
+		}
+		if s.Quirks.IMU != "ZXY" {
+			t.Errorf("%s IMU orientation is %q", c.model, s.Quirks.IMU)
+		}
+		// stored Z, X, Y
+		if a := s.ACCL[0].Value.(ACCL); a != (ACCL{X: 1, Y: 2, Z: 9.8}) {
+			t.Errorf("%s ACCL is %+v", c.model, a)
+		}
+		// stored Y, -X, Z
+		if g := s.GYRO[0].Value.(GYRO); g != (GYRO{X: -0.2, Y: 0.1, Z: 0.3}) {
+			t.Errorf("%s GYRO is %+v", c.model, g)
+		}
+	}
+
+	// older camera without ORIN, samples as stored
+	s := &Session{Telemetry: telem, Quirks: DefaultQuirks}
+	if err := s.readSamples(nil); err != nil {
+		t.Fatal(err)
+	}
+	if a := s.ACCL[0].Value.(ACCL); s.Quirks.IMU != "" || a != (ACCL{X: 9.8, Y: 1, Z: 2}) {
+		t.Errorf("ACCL without orientation is %+v", a)
+	}
+	s = &Session{Quirks: QuirksFor(Camera{Model: "HERO12 Black"})}
+	if err := s.readSamples(nil); err == nil {
+		t.Error("HERO12 has no GPS")
+	}
+}
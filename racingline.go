@@ -0,0 +1,175 @@
+package gokart
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// RACING_LINE_STEP metres between points of a racing line
+const RACING_LINE_STEP = 1.0
+
+// RACING_LINE_WINDOW metres searched around expected position of a sample,
+// avoid matching another part of the track running close by
+const RACING_LINE_WINDOW = 30.0
+
+// RacingLine reference path of a track, average of several laps resampled by distance
+type RacingLine struct {
+	// Laps averaged
+	Laps   []int
+	proj   Projection
+	points Polyline
+}
+
+// BestLaps n fastest complete laps, fastest first
+func (l LapCounter) BestLaps(n int) (laps []int) {
+	laps = l.CompleteLaps()
+	slices.SortStableFunc(laps, func(a, b int) int {
+		return int(l.LapTime(a) - l.LapTime(b))
+	})
+	return laps[:min(n, len(laps))]
+}
+
+// lapPoints GPS samples of lap in tangent plane, with cumulated distance
+func (l LapCounter) lapPoints(gps []Timely, proj Projection, lap int) (points Polyline, distance []float64, err error) {
+	from, to, err := l.LapRange(gps, lap)
+	if err != nil {
+		return
+	}
+	d := 0.0
+	for i := from; i <= to; i++ {
+		p := proj.Point(gps[i].Value.(GPS5))
+		if i > from {
+			d += p.Sub(points[len(points)-1]).Norm()
+		}
+		points = append(points, p)
+		distance = append(distance, d)
+	}
+	return
+}
+
+// resample n points equally spaced along points
+func resample(points Polyline, distance []float64, n int) (out Polyline) {
+	out = make(Polyline, n)
+	total := distance[len(distance)-1]
+	j := 0
+	for i := range n {
+		d := total * float64(i) / float64(n)
+		for j+1 < len(distance)-1 && distance[j+1] < d {
+			j++
+		}
+		seg := distance[j+1] - distance[j]
+		t := 0.0
+		if seg > 0 {
+			t = (d - distance[j]) / seg
+		}
+		out[i] = points[j].Add(points[j+1].Sub(points[j]).Scale(t))
+	}
+	return
+}
+
+// RacingLine average of laps, each lap is resampled by its own distance so
+// that laps of slightly different length are aligned
+func (l LapCounter) RacingLine(gps []Timely, laps []int) (rl RacingLine, err error) {
+	if len(laps) == 0 {
+		return rl, errors.New("no lap for racing line")
+	}
+	rl.Laps = laps
+	rl.proj = l.track.Start.Projection()
+	traces := make([]Polyline, 0, len(laps))
+	distances := make([][]float64, 0, len(laps))
+	length := 0.0
+	for _, lap := range laps {
+		points, distance, perr := l.lapPoints(gps, rl.proj, lap)
+		if perr != nil {
+			return rl, perr
+		}
+		if len(points) < 2 {
+			return rl, fmt.Errorf("lap %d has not enough GPS samples", lap)
+		}
+		traces = append(traces, points)
+		distances = append(distances, distance)
+		length += distance[len(distance)-1]
+	}
+	n := max(2, int(math.Round(length/float64(len(laps))/RACING_LINE_STEP)))
+	rl.points = make(Polyline, n)
+	for i, points := range traces {
+		for j, p := range resample(points, distances[i], n) {
+			rl.points[j] = rl.points[j].Add(p.Scale(1 / float64(len(traces))))
+		}
+	}
+	return
+}
+
+// Length of racing line in metres
+func (rl RacingLine) Length() float64 {
+	return rl.points.Length()
+}
+
+// Positions racing line as GPS positions
+func (rl RacingLine) Positions() (positions []GPS5) {
+	for _, p := range rl.points {
+		positions = append(positions, rl.proj.GPS5(p))
+	}
+	return
+}
+
+// Deviation signed lateral distance in metres from racing line of each GPS
+// sample of lap, positive on the left of the line
+func (l LapCounter) Deviation(gps []Timely, rl RacingLine, lap int) (deviations []float64, err error) {
+	points, distance, err := l.lapPoints(gps, rl.proj, lap)
+	if err != nil {
+		return
+	}
+	n := len(rl.points)
+	total := distance[len(distance)-1]
+	window := int(RACING_LINE_WINDOW/RACING_LINE_STEP) + 1
+	deviations = make([]float64, len(points))
+	for i, p := range points {
+		// expected index from part of lap already done
+		k := 0
+		if total > 0 {
+			k = int(distance[i] / total * float64(n))
+		}
+		best := math.Inf(1)
+		for j := k - window; j <= k+window; j++ {
+			a, b := rl.points[(j%n+n)%n], rl.points[((j+1)%n+n)%n]
+			d, _ := SegmentDistance(p, a, b)
+			if d < best {
+				best = d
+				deviations[i] = d
+				if b.Sub(a).Cross(p.Sub(a)) < 0 {
+					deviations[i] = -d
+				}
+			}
+		}
+	}
+	return
+}
+
+// DeviationMode lateral distance to racing line, only valid to draw lap
+func (l LapCounter) DeviationMode(gps []Timely, rl RacingLine, lap int) (m Mode, err error) {
+	from, _, err := l.LapRange(gps, lap)
+	if err != nil {
+		return
+	}
+	deviations, err := l.Deviation(gps, rl, lap)
+	if err != nil {
+		return
+	}
+	m = Mode{
+		Name:  "line",
+		Label: "Racing line deviation",
+		Unit:  "m",
+		Value: func(gps []Timely, index int) float64 {
+			if index < from || index-from >= len(deviations) {
+				return math.NaN()
+			}
+			return deviations[index-from]
+		},
+		Scale:  Scale{Kind: ScaleSymmetric},
+		Colors: Diverging,
+	}
+	return
+}
@@ -0,0 +1,58 @@
+package gokart
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRacingLine(t *testing.T) {
+	s := circleSession([]float64{10, 12, 11, 12.5})
+	if laps := s.Laps.BestLaps(2); len(laps) != 2 || laps[0] != 2 || laps[1] != 3 {
+		t.Fatalf("wrong best laps %v", laps)
+	}
+	rl, err := s.Laps.RacingLine(s.GPS, []int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := rl.Length(); math.Abs(l-2*math.Pi*100) > 2 {
+		t.Errorf("racing line length %f", l)
+	}
+
+	// lap 3 is 3m wider on its first half, on the right of a counter clockwise circle
+	center := NewGPS5(47.0, 0.2)
+	from, to, _ := s.Laps.LapRange(s.GPS, 3)
+	half := (from + to) / 2
+	for i := from; i < half; i++ {
+		v := s.GPS[i].Value.(GPS5)
+		v.Latitude = center.Latitude + (v.Latitude-center.Latitude)*1.03
+		v.Longitude = center.Longitude + (v.Longitude-center.Longitude)*1.03
+		s.GPS[i].Value = v
+	}
+	deviations, err := s.Laps.Deviation(s.GPS, rl, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deviations) != to-from+1 {
+		t.Fatalf("%d deviations for %d samples", len(deviations), to-from+1)
+	}
+	for _, i := range []int{from + 10, half - 10, half + 10, to - 10} {
+		want := 0.0
+		if i < half {
+			want = -3
+		}
+		if d := deviations[i-from]; math.Abs(d-want) > 0.1 {
+			t.Errorf("sample %d deviation %f should be %f", i, d, want)
+		}
+	}
+
+	m, err := s.Laps.DeviationMode(s.GPS, rl, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(m.Value(s.GPS, from-1)) || m.Value(s.GPS, from+10) != deviations[10] {
+		t.Errorf("wrong mode values")
+	}
+	if _, err = s.Laps.RacingLine(s.GPS, nil); err == nil {
+		t.Errorf("racing line without lap")
+	}
+}
@@ -0,0 +1,135 @@
+package gokart
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// MapCache aerial images loaded once and shared read-only, safe for concurrent use.
+// An image is loaded again when its file modification time changes.
+type MapCache struct {
+	mutex sync.Mutex
+	maps  map[string]*cachedMap
+}
+
+// cachedMap image loaded by first caller, others wait for it
+type cachedMap struct {
+	loaded  chan struct{}
+	modTime time.Time
+	img     *image.RGBA
+	err     error
+}
+
+// NewMapCache empty cache
+func NewMapCache() *MapCache {
+	return &MapCache{maps: make(map[string]*cachedMap)}
+}
+
+// DefaultMapCache used by renderers
+var DefaultMapCache = NewMapCache()
+
+// Get aerial image of track from path, returned image must not be modified.
+// Errors are not cached, image may be added later
+func (c *MapCache) Get(t *Track, path string) (img *image.RGBA, err error) {
+	filename := t.ImageFileName(path)
+	info, err := os.Stat(filename)
+	if err != nil {
+		return
+	}
+	c.mutex.Lock()
+	m, ok := c.maps[filename]
+	if !ok || !m.modTime.Equal(info.ModTime()) {
+		m = &cachedMap{loaded: make(chan struct{}), modTime: info.ModTime()}
+		c.maps[filename] = m
+		c.mutex.Unlock()
+		m.img, m.err = loadMapImage(filename)
+		if m.err != nil {
+			c.mutex.Lock()
+			if c.maps[filename] == m {
+				delete(c.maps, filename)
+			}
+			c.mutex.Unlock()
+		}
+		close(m.loaded)
+	} else {
+		c.mutex.Unlock()
+		<-m.loaded
+	}
+	return m.img, m.err
+}
+
+// Renderer draw on its own copy of a track aerial image,
+// a Renderer must not be shared by goroutines but several can draw the same track
+type Renderer struct {
+	Track  *Track
+	Canvas *image.RGBA
+	base   *image.RGBA
+}
+
+// NewRenderer canvas is a copy of track aerial image from DefaultMapCache
+func NewRenderer(track *Track, path string) (r *Renderer, err error) {
+	base, err := DefaultMapCache.Get(track, path)
+	if err != nil {
+		return
+	}
+	r = &Renderer{Track: track, base: base}
+	r.Reset()
+	return
+}
+
+// Reset canvas to aerial image, to draw another lap
+func (r *Renderer) Reset() {
+	if r.Canvas == nil {
+		r.Canvas = image.NewRGBA(r.base.Bounds())
+	}
+	copy(r.Canvas.Pix, r.base.Pix)
+}
+
+// DrawLap lap trajectory colored by mode with sector times and legend
+func (r *Renderer) DrawLap(l LapCounter, mode Mode, gps []Timely, index int) (err error) {
+	if l.track != r.Track {
+		return fmt.Errorf("lap of %s drawn on %s", l.track.Name, r.Track.Name)
+	}
+	gpsStart, gpsStop, err := l.LapRange(gps, index)
+	if err != nil {
+		return
+	}
+	values := make([]float64, gpsStop-gpsStart+1)
+	for i := range values {
+		values[i] = mode.Value(gps, gpsStart+i)
+	}
+	minMode, maxMode := mode.Scale.Range(values)
+	// draw all gps lines
+	rect := r.Canvas.Bounds()
+	for i := gpsStart; i < gpsStop; i++ {
+		if math.IsNaN(values[i-gpsStart]) {
+			// no value, like delta after end of reference lap
+			continue
+		}
+		x1, y1 := r.Track.PosToXY(rect, gps[i].Value.(GPS5).Latitude, gps[i].Value.(GPS5).Longitude)
+		x2, y2 := r.Track.PosToXY(rect, gps[i+1].Value.(GPS5).Latitude, gps[i+1].Value.(GPS5).Longitude)
+		color := mode.Colors.At(mode.Scale.Ratio(values[i-gpsStart], minMode, maxMode))
+		if mode.Name == "res" {
+			// accuracy in meters
+			accuracy := float64(gps[i].Value.(GPS5).Accuracy) / 100.
+			// radius, accuracy in pixels
+			// accuracy seems not to be so accurate...
+			radius := 3 * accuracy / MeterPerPixel(gps[i].Value.(GPS5).Latitude)
+			DrawCircle(r.Canvas, x1, y1, int(radius+0.5), color)
+		} else {
+			DrawCircleLine(r.Canvas, x1, y1, x2, y2, 3, color)
+		}
+	}
+	l.drawLapLabels(r.Canvas, index)
+	mode.DrawLegend(r.Canvas, minMode, maxMode)
+	return
+}
+
+// DrawZones braking and throttle zones on canvas
+func (r *Renderer) DrawZones(zones []Zone) {
+	r.Track.DrawZones(r.Canvas, zones)
+}
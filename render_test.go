@@ -0,0 +1,95 @@
+package gokart
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRendererConcurrent(t *testing.T) {
+	s := circleSession([]float64{10, 12, 11})
+	s.Track.SetLimits(NewLine(46.998, 0.197, 47.002, 0.203))
+	path := t.TempDir()
+	// uniform aerial image
+	img := image.NewRGBA(image.Rect(0, 0, 300, 300))
+	DrawRectangle(img, 0, 0, 300, 300, color.RGBA{50, 80, 50, 255})
+	f, err := os.Create(s.Track.ImageFileName(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	var wg sync.WaitGroup
+	images := make([]image.Image, 8)
+	for i := range images {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var derr error
+			images[i], derr = s.Laps.DrawLapMode(path, Mode{Name: "speed", Value: GetSpeed, Colors: BlueGreenRed}, s.GPS, 1+i%2)
+			if derr != nil {
+				t.Error(derr)
+			}
+		}()
+	}
+	wg.Wait()
+	base, err := DefaultMapCache.Get(s.Track, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range base.Pix {
+		if base.Pix[i] != img.Pix[i] {
+			t.Fatal("cached map was drawn on")
+		}
+	}
+	// same lap gives same image, nothing drawn over previous laps
+	for i := 2; i < len(images); i++ {
+		if string(images[i].(*image.RGBA).Pix) != string(images[i%2].(*image.RGBA).Pix) {
+			t.Errorf("image %d differs from image %d", i, i%2)
+		}
+	}
+	if s.Track.Map != nil {
+		t.Errorf("shared track was modified")
+	}
+}
+
+func TestMapCacheReload(t *testing.T) {
+	s := circleSession([]float64{10})
+	path := t.TempDir()
+	filename := s.Track.ImageFileName(path)
+	write := func(c color.RGBA, modTime time.Time) {
+		img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+		DrawRectangle(img, 0, 0, 10, 10, c)
+		f, err := os.Create(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(f, img)
+		f.Close()
+		os.Chtimes(filename, modTime, modTime)
+	}
+	cache := NewMapCache()
+	now := time.Now()
+	write(red, now.Add(-time.Hour))
+	first, err := cache.Get(s.Track, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := cache.Get(s.Track, path); again != first {
+		t.Error("image should be loaded once")
+	}
+	// map updated on disk
+	write(blue, now)
+	second, err := cache.Get(s.Track, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first || second.RGBAAt(5, 5) != blue {
+		t.Errorf("changed image not loaded again %v", second.RGBAAt(5, 5))
+	}
+}
@@ -0,0 +1,315 @@
+package gokart
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// ReportOptions what is included in session report
+type ReportOptions struct {
+	// Path for aerial images storage, no map in report when image is missing
+	Path string
+	// Mode used to draw best lap on map
+	Mode string
+	// ChartWidth and ChartHeight size of speed chart in pixels
+	ChartWidth  int
+	ChartHeight int
+}
+
+// DefaultReportOptions speed map and chart
+var DefaultReportOptions = ReportOptions{
+	Path:        ".",
+	Mode:        "speed",
+	ChartWidth:  900,
+	ChartHeight: 300,
+}
+
+// reportSector one sector time with its chrono color
+type reportSector struct {
+	Time  string
+	Color string
+}
+
+// reportLap one line of lap table
+type reportLap struct {
+	Lap     int
+	Time    string
+	Gap     string
+	Best    bool
+	Sectors []reportSector
+}
+
+// report data given to template
+type report struct {
+	Title       string
+	Track       string
+	File        string
+	Date        string
+	Meta        []reportField
+	Laps        []reportLap
+	Sectors     []int
+	BestLap     int
+	BestTime    string
+	Theoretical string
+	BestSectors []string
+	Map         template.URL
+	MapError    string
+	Chart       template.HTML
+}
+
+// reportField label and value of session metadata
+type reportField struct {
+	Label string
+	Value string
+}
+
+// metaFields known metadata of m, in display order
+func metaFields(m Metadata) (fields []reportField) {
+	add := func(label, value string) {
+		if value != "" {
+			fields = append(fields, reportField{label, value})
+		}
+	}
+	add("Driver", m.Driver)
+	add("Kart", m.Kart)
+	add("Chassis", m.Chassis)
+	add("Engine", m.Engine)
+	add("Tyres", m.Tyres)
+	if m.Temperature != nil {
+		add("Temperature", fmt.Sprintf("%.1f °C", *m.Temperature))
+	}
+	add("Camera", strings.TrimSpace(m.Camera.Model+" "+m.Camera.Firmware))
+	add("Notes", m.Notes)
+	return
+}
+
+// htmlColor css color
+func htmlColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// pngDataURL image inlined as png data URL
+func pngDataURL(img image.Image) (url template.URL, err error) {
+	var b bytes.Buffer
+	if err = png.Encode(&b, img); err != nil {
+		return
+	}
+	url = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes()))
+	return
+}
+
+// chartSerie speed along lap distance
+type chartSerie struct {
+	lap      int
+	distance []float64
+	speed    []float64
+}
+
+// speedChart SVG chart of speed (km/h) along distance of each lap, best lap on top
+func (s *Session) speedChart(laps []int, best, width, height int) template.HTML {
+	series := make([]chartSerie, 0, len(laps))
+	maxD, maxS := 0.0, 0.0
+	for _, lap := range laps {
+		from, to, err := s.Laps.LapRange(s.GPS, lap)
+		if err != nil {
+			continue
+		}
+		trace := newLapTrace(s.GPS, s.Laps.LapStart(lap), from, to)
+		sr := chartSerie{lap: lap, distance: trace.distance, speed: make([]float64, len(trace.distance))}
+		for i := range sr.speed {
+			sr.speed[i] = GetSpeed(s.GPS, from+i) * 3.6
+			maxS = math.Max(maxS, sr.speed[i])
+		}
+		maxD = math.Max(maxD, trace.distance[len(trace.distance)-1])
+		series = append(series, sr)
+	}
+	if len(series) == 0 || maxD == 0 || maxS == 0 {
+		return ""
+	}
+	// best lap drawn last to be on top
+	for i := range series {
+		if series[i].lap == best {
+			series = append(series[:i:i], append(series[i+1:], series[i])...)
+			break
+		}
+	}
+	// round axis to 10 km/h and 100 m
+	maxS = math.Ceil(maxS/10) * 10
+	maxD = math.Ceil(maxD/100) * 100
+	const margin = 40
+	w, h := float64(width-2*margin), float64(height-2*margin)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, height, width, height)
+	// grid and axis labels
+	for v := 0.0; v <= maxS; v += 20 {
+		y := margin + h - v/maxS*h
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/>`, margin, y, margin+w, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" font-size="11">%.0f</text>`, margin-4, y+4, v)
+	}
+	step := math.Max(100, math.Ceil(maxD/8/100)*100)
+	for d := 0.0; d <= maxD; d += step {
+		x := margin + d/maxD*w
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle" font-size="11">%.0f</text>`, x, margin+h+16, d)
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11">km/h</text>`, 4, margin-10)
+	fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="end" font-size="11">m</text>`, margin+w, height-4)
+	for _, sr := range series {
+		stroke, sw := "#bbb", 1.0
+		if sr.lap == best {
+			stroke, sw = htmlColor(purple), 2.5
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="%.1f" points="`, stroke, sw)
+		for i := range sr.distance {
+			fmt.Fprintf(&b, "%.1f,%.1f ", margin+sr.distance[i]/maxD*w, margin+h-sr.speed[i]/maxS*h)
+		}
+		fmt.Fprintf(&b, `"><title>Lap %d</title></polyline>`, sr.lap)
+	}
+	b.WriteString("</svg>")
+	// only numbers and constant strings, safe
+	return template.HTML(b.String())
+}
+
+// sectorStatusColor css color of a sector status, text color when unknown
+func sectorStatusColor(status int) string {
+	if status == 0 {
+		return "inherit"
+	}
+	return htmlColor(SectorColor(status))
+}
+
+// WriteReport self-contained HTML page with lap table, best lap map and speed chart,
+// images are inlined so page can be sent by email
+func (s *Session) WriteReport(w io.Writer, opts ReportOptions) (err error) {
+	if s.Track == nil {
+		return ErrUnknownTrack
+	}
+	r := report{
+		Title:   fmt.Sprintf("%s %s", s.Track.Name, s.Start().Format("2006-01-02 15:04")),
+		Track:   s.Track.Name,
+		File:    s.Filename,
+		Date:    s.Start().Format(time.RFC1123),
+		Meta:    metaFields(s.Meta),
+		BestLap: -1,
+	}
+	laps := s.Laps.CompleteLaps()
+	for i := range len(s.Track.Sectors) + 1 {
+		r.Sectors = append(r.Sectors, i+1)
+	}
+	best := s.Laps.Best()
+	bestTime := s.Laps.LapTime(best)
+	if bestTime > 0 {
+		r.BestLap = best
+		r.BestTime = DurationToChrono(bestTime)
+	}
+	if theoretical := s.Laps.TheroreticalBest(); theoretical > 0 {
+		r.Theoretical = DurationToChrono(theoretical)
+	}
+	// best time of each sector among complete laps
+	bestSectors := make([]time.Duration, len(r.Sectors))
+	for _, lap := range laps {
+		for i, d := range s.Laps.SectorTimes(lap) {
+			if d > 0 && (bestSectors[i] == 0 || d < bestSectors[i]) {
+				bestSectors[i] = d
+			}
+		}
+	}
+	for _, d := range bestSectors {
+		r.BestSectors = append(r.BestSectors, DurationToChrono(d))
+	}
+	for _, lap := range laps {
+		d := s.Laps.LapTime(lap)
+		rl := reportLap{Lap: lap, Time: DurationToChrono(d), Best: lap == r.BestLap}
+		if bestTime > 0 && lap != r.BestLap {
+			rl.Gap = "+" + DurationToChrono(d-bestTime)
+		}
+		status := s.Laps.LapStatus(lap)
+		for i, st := range s.Laps.SectorTimes(lap) {
+			c := "inherit"
+			if i < len(status) {
+				c = sectorStatusColor(status[i])
+			}
+			rl.Sectors = append(rl.Sectors, reportSector{DurationToChrono(st), c})
+		}
+		r.Laps = append(r.Laps, rl)
+	}
+	if r.BestLap >= 0 {
+		img, derr := s.Laps.DrawLap(opts.Path, opts.Mode, s.GPS, r.BestLap)
+		if derr != nil {
+			// report is still useful without map
+			r.MapError = derr.Error()
+		} else if r.Map, err = pngDataURL(img); err != nil {
+			return
+		}
+	}
+	r.Chart = s.speedChart(laps, r.BestLap, opts.ChartWidth, opts.ChartHeight)
+	if err = reportTemplate.Execute(w, r); err != nil {
+		err = fmt.Errorf("unable to write report:%s", err)
+	}
+	return
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 960px; color: #222; }
+h1 { margin-bottom: 0; }
+.sub { color: #777; margin-top: 0.2em; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { padding: 0.3em 0.8em; text-align: right; font-family: monospace; font-size: 1.1em; }
+th { border-bottom: 2px solid #222; font-family: sans-serif; }
+tr:nth-child(even) { background: #f4f4f4; }
+tr.best { font-weight: bold; }
+tfoot td { border-top: 2px solid #222; }
+.legend span { display: inline-block; margin-right: 1.5em; }
+img { max-width: 100%; }
+table.meta th { border-bottom: none; text-align: left; }
+table.meta td { text-align: left; font-family: sans-serif; }
+</style>
+</head>
+<body>
+<h1>{{.Track}}</h1>
+<p class="sub">{{.Date}} &mdash; {{.File}}</p>
+{{if .Meta}}
+<table class="meta">
+{{range .Meta}}<tr><th>{{.Label}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Laps}}
+<h2>Laps</h2>
+<table>
+<thead><tr><th>Lap</th><th>Time</th><th>Gap</th>{{range .Sectors}}<th>S{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Laps}}<tr{{if .Best}} class="best"{{end}}><td>{{.Lap}}</td><td>{{.Time}}</td><td>{{.Gap}}</td>{{range .Sectors}}<td style="color:{{.Color}}">{{.Time}}</td>{{end}}</tr>
+{{end}}</tbody>
+<tfoot><tr><td>Best</td><td>{{.BestTime}}</td><td></td>{{range .BestSectors}}<td>{{.}}</td>{{end}}</tr></tfoot>
+</table>
+<p class="legend"><span style="color:#aa00ff">&#9632; best sector</span><span style="color:#00ff00">&#9632; improved</span><span style="color:#ff0000">&#9632; slower</span></p>
+{{if .Theoretical}}<p>Theoretical best: <strong>{{.Theoretical}}</strong></p>{{end}}
+{{else}}
+<p>No complete lap.</p>
+{{end}}
+{{if .Map}}
+<h2>Best lap {{.BestLap}}</h2>
+<img src="{{.Map}}" alt="best lap map">
+{{else if .MapError}}
+<p class="sub">No map: {{.MapError}}</p>
+{{end}}
+{{if .Chart}}
+<h2>Speed</h2>
+{{.Chart}}
+{{end}}
+</body>
+</html>
+`))
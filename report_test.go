@@ -0,0 +1,41 @@
+package gokart
+
+import (
+	"strings"
+	"testing"
+)
+
+// circleSession session of laps around the circle of circleLaps
+func circleSession(speeds []float64) (s *Session) {
+	track, gps, laps := circleLaps(speeds)
+	return &Session{Filename: "circle.mp4", GPS: gps, Track: track, Laps: laps}
+}
+
+func TestWriteReport(t *testing.T) {
+	// last lap is not complete
+	s := circleSession([]float64{10, 12, 11, 12.5})
+	if laps := s.Laps.CompleteLaps(); len(laps) != 3 {
+		t.Fatalf("found %d complete laps should be 3", len(laps))
+	}
+	if s.Laps.Best() != 2 {
+		t.Errorf("best lap is %d should be 2", s.Laps.Best())
+	}
+	// slower than best lap in every sector
+	for i, status := range s.Laps.LapStatus(3) {
+		if status != -1 {
+			t.Errorf("lap 3 sector %d status is %d should be -1", i+1, status)
+		}
+	}
+	var b strings.Builder
+	opts := DefaultReportOptions
+	opts.Path = t.TempDir()
+	if err := s.WriteReport(&b, opts); err != nil {
+		t.Fatal(err)
+	}
+	html := b.String()
+	for _, want := range []string{"<h1>Circle</h1>", "<th>S3</th>", "color:#aa00ff", "<svg", "No map:"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+}
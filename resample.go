@@ -0,0 +1,232 @@
+package gokart
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Interpolator values at xs of a scalar channel known as y at sorted x,
+// xs are sorted and inside [x[0] x[len(x)-1]]
+type Interpolator func(x, y, xs []float64) (ys []float64)
+
+// Linear straight line between samples
+func Linear(x, y, xs []float64) (ys []float64) {
+	ys = make([]float64, len(xs))
+	j := 0
+	for i, v := range xs {
+		for j+2 < len(x) && x[j+1] < v {
+			j++
+		}
+		t := (v - x[j]) / (x[j+1] - x[j])
+		ys[i] = y[j] + t*(y[j+1]-y[j])
+	}
+	return
+}
+
+// CubicSpline natural cubic spline through all samples, smooth speed and
+// acceleration curves but may overshoot on sharp changes
+func CubicSpline(x, y, xs []float64) (ys []float64) {
+	n := len(x)
+	if n < 3 {
+		return Linear(x, y, xs)
+	}
+	// second derivatives, tridiagonal system solved with Thomas algorithm
+	m := make([]float64, n)
+	c := make([]float64, n)
+	d := make([]float64, n)
+	for i := 1; i < n-1; i++ {
+		h0, h1 := x[i]-x[i-1], x[i+1]-x[i]
+		a, b := h0, 2*(h0+h1)
+		r := 6 * ((y[i+1]-y[i])/h1 - (y[i]-y[i-1])/h0)
+		den := b - a*c[i-1]
+		c[i] = h1 / den
+		d[i] = (r - a*d[i-1]) / den
+	}
+	for i := n - 2; i > 0; i-- {
+		m[i] = d[i] - c[i]*m[i+1]
+	}
+	ys = make([]float64, len(xs))
+	j := 0
+	for i, v := range xs {
+		for j+2 < n && x[j+1] < v {
+			j++
+		}
+		h := x[j+1] - x[j]
+		a, b := (x[j+1]-v)/h, (v-x[j])/h
+		ys[i] = a*y[j] + b*y[j+1] + ((a*a*a-a)*m[j]+(b*b*b-b)*m[j+1])*h*h/6
+	}
+	return
+}
+
+// channelCodec split values of one type in scalar channels and back
+type channelCodec struct {
+	split func(v any) []float64
+	// join values of channels, sample just before gives non interpolated fields
+	join func(values []float64, before any) any
+}
+
+// codecOf series values, GPS5, ACCL and float64 are supported
+func codecOf(v any) (c channelCodec, err error) {
+	switch v.(type) {
+	case GPS5:
+		c.split = func(v any) []float64 {
+			g := v.(GPS5)
+			return []float64{g.Latitude, g.Longitude, g.Altitude, g.Speed, g.Speed3D}
+		}
+		c.join = func(values []float64, before any) any {
+			g := before.(GPS5)
+			g.Latitude, g.Longitude, g.Altitude, g.Speed, g.Speed3D = values[0], values[1], values[2], values[3], values[4]
+			return g
+		}
+	case ACCL:
+		c.split = func(v any) []float64 {
+			a := v.(ACCL)
+			return []float64{a.X, a.Y, a.Z}
+		}
+		c.join = func(values []float64, before any) any {
+			return ACCL{X: values[0], Y: values[1], Z: values[2]}
+		}
+	case float64:
+		c.split = func(v any) []float64 {
+			return []float64{v.(float64)}
+		}
+		c.join = func(values []float64, before any) any {
+			return values[0]
+		}
+	default:
+		err = fmt.Errorf("%T resampling not implemented", v)
+	}
+	return
+}
+
+// resampleAt values of series at xs, x is position of each sample and must
+// be increasing, times are always interpolated linearly
+func resampleAt(series []Timely, x, xs []float64, interp Interpolator) (out []Timely, err error) {
+	codec, err := codecOf(series[0].Value)
+	if err != nil {
+		return
+	}
+	var channels [][]float64
+	seconds := make([]float64, len(series))
+	for i, s := range series {
+		values := codec.split(s.Value)
+		if channels == nil {
+			channels = make([][]float64, len(values))
+		}
+		for c, v := range values {
+			channels[c] = append(channels[c], v)
+		}
+		seconds[i] = s.Time.Sub(series[0].Time).Seconds()
+	}
+	results := make([][]float64, len(channels))
+	for c, y := range channels {
+		results[c] = interp(x, y, xs)
+	}
+	times := Linear(x, seconds, xs)
+	out = make([]Timely, len(xs))
+	values := make([]float64, len(channels))
+	j := 0
+	for i, v := range xs {
+		for j+1 < len(x) && x[j+1] <= v {
+			j++
+		}
+		for c := range results {
+			values[c] = results[c][i]
+		}
+		out[i] = Timely{
+			Time:  series[0].Time.Add(time.Duration(times[i]*float64(time.Second) + 0.5)),
+			Value: codec.join(values, series[j].Value),
+		}
+	}
+	return
+}
+
+// increasing keep samples whose position is strictly after previous kept one
+func increasing(series []Timely, x []float64) (kept []Timely, kx []float64) {
+	for i := range series {
+		if len(kx) == 0 || x[i] > kx[len(kx)-1] {
+			kept = append(kept, series[i])
+			kx = append(kx, x[i])
+		}
+	}
+	return
+}
+
+// Resample series every step from its first sample time, values must all be
+// GPS5, ACCL or float64, series sorted by time
+func Resample(series []Timely, step time.Duration, interp Interpolator) (out []Timely, err error) {
+	if step <= 0 {
+		return nil, errors.New("resampling step must be positive")
+	}
+	if len(series) < 2 {
+		return nil, errors.New("not enough samples to resample")
+	}
+	x := make([]float64, len(series))
+	for i, s := range series {
+		x[i] = s.Time.Sub(series[0].Time).Seconds()
+	}
+	series, x = increasing(series, x)
+	if len(series) < 2 {
+		return nil, errors.New("not enough samples to resample")
+	}
+	var xs []float64
+	for t := time.Duration(0); t.Seconds() <= x[len(x)-1]; t += step {
+		xs = append(xs, t.Seconds())
+	}
+	return resampleAt(series, x, xs, interp)
+}
+
+// ResampleByDistance GPS5 series every metres of travelled distance from its
+// first sample, samples while kart is stopped are skipped
+func ResampleByDistance(gps []Timely, metres float64, interp Interpolator) (out []Timely, err error) {
+	if metres <= 0 {
+		return nil, errors.New("resampling distance must be positive")
+	}
+	if len(gps) < 2 {
+		return nil, errors.New("not enough samples to resample")
+	}
+	if _, ok := gps[0].Value.(GPS5); !ok {
+		return nil, fmt.Errorf("%T has no distance", gps[0].Value)
+	}
+	x := make([]float64, len(gps))
+	for i := 1; i < len(gps); i++ {
+		x[i] = x[i-1] + Distance(gps[i-1].Value.(GPS5), gps[i].Value.(GPS5))
+	}
+	gps, x = increasing(gps, x)
+	if len(gps) < 2 {
+		return nil, errors.New("not enough distance to resample")
+	}
+	n := int(x[len(x)-1]/metres) + 1
+	xs := make([]float64, n)
+	for i := range xs {
+		xs[i] = float64(i) * metres
+	}
+	return resampleAt(gps, x, xs, interp)
+}
+
+// ResampleAt values of series at given times, times out of series range are skipped
+func ResampleAt(series []Timely, times []time.Time, interp Interpolator) (out []Timely, err error) {
+	if len(series) < 2 {
+		return nil, errors.New("not enough samples to resample")
+	}
+	x := make([]float64, len(series))
+	for i, s := range series {
+		x[i] = s.Time.Sub(series[0].Time).Seconds()
+	}
+	series, x = increasing(series, x)
+	if len(series) < 2 {
+		return nil, errors.New("not enough samples to resample")
+	}
+	xs := make([]float64, 0, len(times))
+	for _, t := range times {
+		if v := t.Sub(series[0].Time).Seconds(); v >= 0 && v <= x[len(x)-1] {
+			xs = append(xs, v)
+		}
+	}
+	if !sort.Float64sAreSorted(xs) {
+		return nil, errors.New("times must be sorted")
+	}
+	return resampleAt(series, x, xs, interp)
+}
@@ -0,0 +1,71 @@
+package gokart
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestResample(t *testing.T) {
+	t0 := time.Date(2024, 9, 14, 11, 12, 0, 0, time.UTC)
+	// irregular samples of sin
+	var series []Timely
+	for _, ms := range []int{0, 50, 130, 180, 260, 330, 400, 470, 560, 610, 700, 780, 850, 930, 1000} {
+		x := float64(ms) / 1000
+		series = append(series, Timely{Time: t0.Add(time.Duration(ms) * time.Millisecond), Value: math.Sin(2 * x)})
+	}
+	for name, c := range map[string]struct {
+		interp Interpolator
+		error  float64
+	}{
+		"linear": {Linear, 5e-3},
+		"spline": {CubicSpline, 1e-3},
+	} {
+		out, err := Resample(series, 100*time.Millisecond, c.interp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 11 {
+			t.Fatalf("%s: %d samples should be 11", name, len(out))
+		}
+		for i, o := range out {
+			if want := t0.Add(time.Duration(i) * 100 * time.Millisecond); !o.Time.Equal(want) {
+				t.Errorf("%s: sample %d at %s", name, i, o.Time)
+			}
+			// natural spline is less precise at ends
+			if i > 0 && i < 10 && math.Abs(o.Value.(float64)-math.Sin(0.2*float64(i))) > c.error {
+				t.Errorf("%s: sample %d is %f should be %f", name, i, o.Value, math.Sin(0.2*float64(i)))
+			}
+		}
+	}
+
+	accl := []Timely{{Time: t0, Value: ACCL{X: 1}}, {Time: t0.Add(time.Second), Value: ACCL{X: 3, Z: 10}}}
+	out, err := Resample(accl, 250*time.Millisecond, Linear)
+	if err != nil || len(out) != 5 || out[2].Value.(ACCL) != (ACCL{X: 2, Z: 5}) {
+		t.Errorf("wrong ACCL resampling %v %v", out, err)
+	}
+	if _, err = Resample([]Timely{{Time: t0, Value: "a"}, {Time: t0.Add(time.Second), Value: "b"}}, time.Second, Linear); err == nil {
+		t.Errorf("unsupported type resampled")
+	}
+	// samples at same time are one point
+	if _, err = ResampleAt([]Timely{{Time: t0, Value: 1.0}, {Time: t0, Value: 2.0}}, []time.Time{t0}, Linear); err == nil {
+		t.Errorf("single time resampled")
+	}
+
+	// every 10m around a 100m radius circle, time follows speed
+	s := circleSession([]float64{10, 20})
+	out, err = ResampleByDistance(s.GPS, 10, CubicSpline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(out); i++ {
+		if d := Distance(out[i-1].Value.(GPS5), out[i].Value.(GPS5)); math.Abs(d-10) > 0.05 {
+			t.Fatalf("samples %d and %d are %fm apart", i-1, i, d)
+		}
+	}
+	first, second := out[1].Time.Sub(out[0].Time), out[len(out)-1].Time.Sub(out[len(out)-2].Time)
+	// circle of test is built with an approximate metres per degree
+	if first.Round(10*time.Millisecond) != time.Second || second.Round(10*time.Millisecond) != 500*time.Millisecond {
+		t.Errorf("wrong times between samples %s and %s", first, second)
+	}
+}
@@ -0,0 +1,309 @@
+package gokart
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed web
+var webContent embed.FS
+
+// Server local HTTP server exposing sessions of a directory as JSON API,
+// with an embedded web viewer
+type Server struct {
+	// Dir where GoPro videos are read
+	Dir string
+	// Store optional cache of parsed sessions
+	Store *Store
+	mux   *http.ServeMux
+
+	mutex    sync.Mutex
+	sessions map[string]*serverSession
+}
+
+// serverSession loaded session, reloaded when file changes
+type serverSession struct {
+	modTime time.Time
+	session *Session
+	err     error
+	loading chan struct{}
+}
+
+// NewServer serving videos of dir
+func NewServer(dir string) (s *Server) {
+	s = &Server{
+		Dir:      dir,
+		mux:      http.NewServeMux(),
+		sessions: make(map[string]*serverSession),
+	}
+	web, _ := fs.Sub(webContent, "web")
+	s.mux.Handle("GET /", http.FileServerFS(web))
+	s.mux.HandleFunc("GET /api/tracks", s.handleTracks)
+	s.mux.HandleFunc("GET /api/modes", s.handleModes)
+	s.mux.HandleFunc("GET /api/sessions", s.handleSessions)
+	s.mux.HandleFunc("GET /api/sessions/{id}", s.handleSession)
+	s.mux.HandleFunc("GET /api/sessions/{id}/laps/{lap}", s.handleLap)
+	return
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// jsonFloat float encoded as null when not a number
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+		return []byte("null"), nil
+	}
+	return strconv.AppendFloat(nil, float64(f), 'f', -1, 64), nil
+}
+
+// writeJSON v as response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError error as json response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// isVideo GoPro video file name
+func isVideo(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".mp4")
+}
+
+// isSessionFile GoPro video or GPS log file name
+func isSessionFile(name string) bool {
+	return isVideo(name) || IsGPSLog(name)
+}
+
+// errNotFound unknown session or lap
+var errNotFound = errors.New("not found")
+
+// session loaded once, concurrent requests wait for first load
+func (s *Server) session(id string) (session *Session, err error) {
+	// only files directly in Dir
+	if id != filepath.Base(id) || !isSessionFile(id) {
+		return nil, errNotFound
+	}
+	filename := filepath.Join(s.Dir, id)
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, errNotFound
+	}
+	s.mutex.Lock()
+	ss, ok := s.sessions[id]
+	if !ok || !ss.modTime.Equal(info.ModTime()) {
+		ss = &serverSession{modTime: info.ModTime(), loading: make(chan struct{})}
+		s.sessions[id] = ss
+		s.mutex.Unlock()
+		if s.Store != nil {
+			ss.session, ss.err = s.Store.Load(filename)
+		} else {
+			ss.session, ss.err = LoadSession(filename)
+		}
+		close(ss.loading)
+	} else {
+		s.mutex.Unlock()
+		<-ss.loading
+	}
+	return ss.session, ss.err
+}
+
+// sessionInfo one video in sessions list
+type sessionInfo struct {
+	ID      string    `json:"id"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modtime"`
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	list := make([]sessionInfo, 0)
+	for _, e := range entries {
+		if e.IsDir() || !isSessionFile(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		list = append(list, sessionInfo{e.Name(), info.Size(), info.ModTime()})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ModTime.After(list[j].ModTime) })
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleTracks(w http.ResponseWriter, r *http.Request) {
+	// aerial maps are not sent
+	tracks := make([]Track, len(TheWorld.Tracks))
+	for i, t := range TheWorld.Tracks {
+		tracks[i] = *t
+		tracks[i].Map = nil
+	}
+	writeJSON(w, http.StatusOK, tracks)
+}
+
+// modeInfo registered mode usable as lap channel
+type modeInfo struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Unit  string `json:"unit"`
+}
+
+func (s *Server) handleModes(w http.ResponseWriter, r *http.Request) {
+	list := make([]modeInfo, 0)
+	for _, name := range Modes() {
+		m, _ := GetMode(name)
+		list = append(list, modeInfo{m.Name, m.Label, m.Unit})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// apiLap lap summary, durations in milliseconds
+type apiLap struct {
+	Lap     int     `json:"lap"`
+	Start   string  `json:"start"`
+	Time    int64   `json:"time"`
+	Error   int64   `json:"error"`
+	Sectors []int64 `json:"sectors"`
+	Status  []int   `json:"status"`
+}
+
+// apiSession session summary
+type apiSession struct {
+	ID          string   `json:"id"`
+	Track       *Track   `json:"track"`
+	Start       string   `json:"start"`
+	Laps        []apiLap `json:"laps"`
+	Best        int      `json:"best"`
+	Theoretical int64    `json:"theoretical"`
+	Meta        Metadata `json:"meta"`
+}
+
+// loadSession from request id, write error response when false
+func (s *Server) loadSession(w http.ResponseWriter, r *http.Request) (session *Session, ok bool) {
+	session, err := s.session(r.PathValue("id"))
+	switch {
+	case err == errNotFound:
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrUnknownTrack):
+		writeError(w, http.StatusUnprocessableEntity, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		ok = true
+	}
+	return
+}
+
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.loadSession(w, r)
+	if !ok {
+		return
+	}
+	// track without aerial map
+	track := *session.Track
+	track.Map = nil
+	out := apiSession{
+		ID:          r.PathValue("id"),
+		Track:       &track,
+		Start:       session.Start().Format(time.RFC3339Nano),
+		Laps:        make([]apiLap, 0),
+		Best:        -1,
+		Theoretical: session.Laps.TheroreticalBest().Milliseconds(),
+		Meta:        session.Meta,
+	}
+	if session.Laps.BestTime() > 0 {
+		out.Best = session.Laps.Best()
+	}
+	for _, lap := range session.Laps.CompleteLaps() {
+		l := apiLap{
+			Lap:    lap,
+			Start:  session.Laps.LapStart(lap).Format(time.RFC3339Nano),
+			Time:   session.Laps.LapTime(lap).Milliseconds(),
+			Error:  session.Laps.LapError(lap).Milliseconds(),
+			Status: session.Laps.LapStatus(lap),
+		}
+		for _, d := range session.Laps.SectorTimes(lap) {
+			l.Sectors = append(l.Sectors, d.Milliseconds())
+		}
+		out.Laps = append(out.Laps, l)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// apiChannels samples of one lap, each channel has one value per sample
+type apiChannels struct {
+	Lap int `json:"lap"`
+	// Time in seconds from lap start, Distance in meters from start line
+	Time      []jsonFloat            `json:"time"`
+	Distance  []jsonFloat            `json:"distance"`
+	Latitude  []jsonFloat            `json:"latitude"`
+	Longitude []jsonFloat            `json:"longitude"`
+	Channels  map[string][]jsonFloat `json:"channels"`
+}
+
+func (s *Server) handleLap(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.loadSession(w, r)
+	if !ok {
+		return
+	}
+	lap, err := strconv.Atoi(r.PathValue("lap"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	from, to, err := session.Laps.LapRange(session.GPS, lap)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	trace := newLapTrace(session.GPS, session.Laps.LapStart(lap), from, to)
+	n := to - from + 1
+	out := apiChannels{
+		Lap:       lap,
+		Time:      make([]jsonFloat, n),
+		Distance:  make([]jsonFloat, n),
+		Latitude:  make([]jsonFloat, n),
+		Longitude: make([]jsonFloat, n),
+		Channels:  make(map[string][]jsonFloat),
+	}
+	for i := range n {
+		g := session.GPS[from+i].Value.(GPS5)
+		out.Time[i] = jsonFloat(trace.elapsed[i].Seconds())
+		out.Distance[i] = jsonFloat(trace.distance[i])
+		out.Latitude[i] = jsonFloat(g.Latitude)
+		out.Longitude[i] = jsonFloat(g.Longitude)
+	}
+	for _, name := range Modes() {
+		m, _ := GetMode(name)
+		values := make([]jsonFloat, n)
+		for i := range n {
+			values[i] = jsonFloat(m.Display(m.Value(session.GPS, from+i)))
+		}
+		out.Channels[name] = values
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,75 @@
+package gokart
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.MP4", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := NewServer(dir)
+	// already loaded session, no video needed
+	info, _ := os.Stat(filepath.Join(dir, "b.MP4"))
+	loaded := &serverSession{modTime: info.ModTime(), session: circleSession([]float64{10, 12, 11}), loading: make(chan struct{})}
+	close(loaded.loading)
+	server.sessions["b.MP4"] = loaded
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	get := func(path string, v any) int {
+		r, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+		if v != nil {
+			if err = json.NewDecoder(r.Body).Decode(v); err != nil {
+				t.Fatalf("%s:%s", path, err)
+			}
+		}
+		return r.StatusCode
+	}
+	var sessions []sessionInfo
+	if get("/api/sessions", &sessions); len(sessions) != 1 || sessions[0].ID != "b.MP4" {
+		t.Errorf("wrong sessions %v", sessions)
+	}
+	var tracks []Track
+	if get("/api/tracks", &tracks); len(tracks) != len(TheWorld.Tracks) {
+		t.Errorf("found %d tracks should be %d", len(tracks), len(TheWorld.Tracks))
+	}
+	var session apiSession
+	if get("/api/sessions/b.MP4", &session); len(session.Laps) != 2 || session.Best != 2 || session.Track.Name != "Circle" {
+		t.Errorf("wrong session %+v", session)
+	}
+	var channels struct {
+		Distance []float64
+		Channels map[string][]*float64
+	}
+	if get("/api/sessions/b.MP4/laps/2", &channels); len(channels.Distance) == 0 || len(channels.Channels["speed"]) != len(channels.Distance) {
+		t.Errorf("wrong lap channels %d distances", len(channels.Distance))
+	}
+	for _, path := range []string{"/api/sessions/notes.txt", "/api/sessions/..%2Fx.mp4", "/api/sessions/missing.mp4/laps/1"} {
+		if status := get(path, nil); status != http.StatusNotFound {
+			t.Errorf("%s status is %d should be 404", path, status)
+		}
+	}
+	r, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	var b strings.Builder
+	if _, err = io.Copy(&b, r.Body); err != nil || !strings.Contains(b.String(), "<canvas") {
+		t.Errorf("index page not served")
+	}
+}
@@ -0,0 +1,122 @@
+package gokart
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cedricjoulain/gopro-utils/telemetry"
+)
+
+// ErrUnknownTrack no known track close to GPS positions
+var ErrUnknownTrack = errors.New("unknown track")
+
+// Session everything read from one GoPro video
+type Session struct {
+	Filename  string
+	Telemetry []*telemetry.TELEM
+	GPS       []Timely
+	ACCL      []Timely
+	GYRO      []Timely
+	Track     *Track
+	Laps      LapCounter
+	Meta      Metadata
+	// Quirks of camera, applied when telemetry is read
+	Quirks Quirks
+	// channels computed so far, see Channels
+	channels *Channels
+}
+
+// LoadSession read telemetry of filename, find track and count laps silently,
+// when track is unknown session is returned with ErrUnknownTrack. NMEA and CSV
+// logs (see IsGPSLog) are read as GPS only sessions.
+func LoadSession(filename string) (s *Session, err error) {
+	if IsGPSLog(filename) {
+		return loadGPSLog(filename)
+	}
+	s = &Session{Filename: filename}
+	if s.Meta, err = ReadMetadata(filename); err != nil {
+		return
+	}
+	s.Quirks = QuirksFor(s.Meta.Camera)
+	gpmd, err := ReadGoProStream(filename)
+	if err == nil {
+		s.Telemetry, err = ParseTelemetry(gpmd)
+	}
+	if err != nil {
+		err = fmt.Errorf("unable to get GoPro telemetry of %s:%w", filename, err)
+		return
+	}
+	if err = s.readSamples(gpmd); err != nil {
+		err = fmt.Errorf("unable to read samples of %s:%w", filename, err)
+		return
+	}
+	err = s.countLaps()
+	return
+}
+
+// readSamples GPS, ACCL and GYRO samples of parsed telemetry and raw gpmd,
+// GPS stream is chosen according to camera quirks and IMU axes are reordered
+// as written in video
+func (s *Session) readSamples(gpmd []byte) (err error) {
+	readers := map[string]func() ([]Timely, error){
+		"GPS5": func() ([]Timely, error) { return GpsWithTime(s.Telemetry), nil },
+		"GPS9": func() ([]Timely, error) { return GPS9WithTime(gpmd) },
+	}
+	order := []string{"GPS5", "GPS9"}
+	if s.Quirks.GPS == "GPS9" {
+		order = []string{"GPS9", "GPS5"}
+	}
+	for _, stream := range order {
+		if s.GPS, err = readers[stream](); err != nil {
+			return fmt.Errorf("unable to read %s:%w", stream, err)
+		}
+		if len(s.GPS) > 0 {
+			break
+		}
+	}
+	if len(s.GPS) == 0 {
+		if qerr := s.Quirks.checkGPS(); qerr != nil {
+			return fmt.Errorf("no GPS:%w", qerr)
+		}
+	}
+	orientations := ReadOrientations(gpmd)
+	s.Quirks.IMU = orientations["ACCL"]
+	s.ACCL = AcclWithTime(s.Telemetry)
+	for i, a := range s.ACCL {
+		s.ACCL[i].Value = orientations["ACCL"].ACCL(a.Value.(ACCL))
+	}
+	s.GYRO = GyroWithTime(s.Telemetry)
+	for i, g := range s.GYRO {
+		s.GYRO[i].Value = orientations["GYRO"].GYRO(g.Value.(GYRO))
+	}
+	return
+}
+
+// countLaps find track from GPS samples and count laps silently
+func (s *Session) countLaps() (err error) {
+	if len(s.GPS) < 2 {
+		return fmt.Errorf("not enough GPS points in %s", s.Filename)
+	}
+	track := TheWorld.GetTrack(s.GPS)
+	if track == nil {
+		return ErrUnknownTrack
+	}
+	s.CountLaps(track)
+	return
+}
+
+// CountLaps set track and count laps silently
+func (s *Session) CountLaps(track *Track) {
+	s.Track = track
+	s.Laps = NewLapCounter(track)
+	s.Laps.SetOutput(nil)
+	for i := 1; i < len(s.GPS); i++ {
+		s.Laps.Update(s.GPS[i-1].Time, s.GPS[i-1], s.GPS[i])
+	}
+}
+
+// Start first GPS time, used as video start
+func (s *Session) Start() time.Time {
+	return s.GPS[0].Time
+}
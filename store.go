@@ -0,0 +1,471 @@
+package gokart
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HASH_CHUNK bytes read at beginning and end of a video to compute its hash
+const HASH_CHUNK = 4 << 20
+
+// FileHash identify a video without reading it entirely, sha256 of size,
+// first and last HASH_CHUNK bytes, GoPro videos are never modified in place
+func FileHash(filename string) (hash string, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n", info.Size())
+	if _, err = io.CopyN(h, f, HASH_CHUNK); err != nil && err != io.EOF {
+		return
+	}
+	if info.Size() > 2*HASH_CHUNK {
+		if _, err = f.Seek(-HASH_CHUNK, io.SeekEnd); err != nil {
+			return
+		}
+		if _, err = io.CopyN(h, f, HASH_CHUNK); err != nil {
+			return
+		}
+	}
+	err = nil
+	hash = hex.EncodeToString(h.Sum(nil))
+	return
+}
+
+// LapRecord one complete lap of a stored session
+type LapRecord struct {
+	Hash    string          `json:"hash"`
+	File    string          `json:"file"`
+	Track   string          `json:"track"`
+	Driver  string          `json:"driver,omitempty"`
+	Lap     int             `json:"lap"`
+	Start   time.Time       `json:"start"`
+	Time    time.Duration   `json:"time"`
+	Sectors []time.Duration `json:"sectors"`
+}
+
+// StoreEntry what is known of a stored session without loading its samples
+type StoreEntry struct {
+	Hash   string    `json:"hash"`
+	File   string    `json:"file"`
+	Size   int64     `json:"size"`
+	Track  string    `json:"track,omitempty"`
+	Driver string    `json:"driver,omitempty"`
+	Start  time.Time `json:"start"`
+	Added  time.Time `json:"added"`
+	// Meta as last read from sidecar and camera
+	Meta Metadata `json:"meta"`
+	// Best lap number, -1 without complete lap
+	Best int         `json:"best"`
+	Laps []LapRecord `json:"laps"`
+}
+
+// SAMPLES_VERSION of saved samples, older files are read again from video
+const SAMPLES_VERSION = 2
+
+// storedSamples parsed telemetry saved in <hash>.gob
+type storedSamples struct {
+	Version   int
+	GPSTimes  []time.Time
+	GPS       []GPS5
+	ACCLTimes []time.Time
+	ACCL      []ACCL
+	GYROTimes []time.Time
+	GYRO      []GYRO
+	// IMU orientation already applied to ACCL
+	IMU Orientation
+}
+
+// Store sessions cache in a directory, parsed samples in one gob file per video
+// and an index.json with tracks and laps of all sessions
+type Store struct {
+	Dir   string
+	mutex sync.Mutex
+	index map[string]*StoreEntry
+}
+
+// OpenStore create dir if needed and read its index
+func OpenStore(dir string) (st *Store, err error) {
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return
+	}
+	st = &Store{Dir: dir, index: make(map[string]*StoreEntry)}
+	data, err := os.ReadFile(st.indexFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return
+	}
+	entries := make([]*StoreEntry, 0)
+	if err = json.Unmarshal(data, &entries); err != nil {
+		err = fmt.Errorf("unable to unmarshal store index:%s", err)
+		return
+	}
+	for _, e := range entries {
+		st.index[e.Hash] = e
+	}
+	return
+}
+
+func (st *Store) indexFile() string {
+	return filepath.Join(st.Dir, "index.json")
+}
+
+func (st *Store) samplesFile(hash string) string {
+	return filepath.Join(st.Dir, hash+".gob")
+}
+
+// saveIndex write index atomically, mutex must be held
+func (st *Store) saveIndex() (err error) {
+	data, err := json.MarshalIndent(st.entries(), "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := st.indexFile() + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	return os.Rename(tmp, st.indexFile())
+}
+
+// entries sorted by session start, mutex must be held
+func (st *Store) entries() (entries []*StoreEntry) {
+	entries = make([]*StoreEntry, 0, len(st.index))
+	for _, e := range st.index {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Start.Equal(entries[j].Start) {
+			return entries[i].Hash < entries[j].Hash
+		}
+		return entries[i].Start.Before(entries[j].Start)
+	})
+	return
+}
+
+// Entries all stored sessions sorted by start
+func (st *Store) Entries() (entries []StoreEntry) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	for _, e := range st.entries() {
+		entries = append(entries, *e)
+	}
+	return
+}
+
+// newStoreEntry index entry from loaded session
+func newStoreEntry(hash string, size int64, s *Session) (e *StoreEntry) {
+	e = &StoreEntry{
+		Hash:   hash,
+		File:   s.Filename,
+		Size:   size,
+		Driver: s.Meta.Driver,
+		Start:  s.Start(),
+		Added:  time.Now(),
+		Meta:   s.Meta,
+		Best:   -1,
+		Laps:   make([]LapRecord, 0),
+	}
+	if s.Track == nil {
+		return
+	}
+	e.Track = s.Track.Name
+	if s.Laps.BestTime() > 0 {
+		e.Best = s.Laps.Best()
+	}
+	for _, lap := range s.Laps.CompleteLaps() {
+		e.Laps = append(e.Laps, LapRecord{
+			Hash:    hash,
+			File:    s.Filename,
+			Track:   e.Track,
+			Lap:     lap,
+			Start:   s.Laps.LapStart(lap),
+			Time:    s.Laps.LapTime(lap),
+			Sectors: s.Laps.SectorTimes(lap),
+		})
+	}
+	return
+}
+
+// saveSamples write parsed samples of s
+func (st *Store) saveSamples(hash string, s *Session) (err error) {
+	samples := storedSamples{
+		Version:   SAMPLES_VERSION,
+		GPSTimes:  make([]time.Time, len(s.GPS)),
+		GPS:       make([]GPS5, len(s.GPS)),
+		ACCLTimes: make([]time.Time, len(s.ACCL)),
+		ACCL:      make([]ACCL, len(s.ACCL)),
+		GYROTimes: make([]time.Time, len(s.GYRO)),
+		GYRO:      make([]GYRO, len(s.GYRO)),
+		IMU:       s.Quirks.IMU,
+	}
+	for i, g := range s.GPS {
+		samples.GPSTimes[i], samples.GPS[i] = g.Time, g.Value.(GPS5)
+	}
+	for i, a := range s.ACCL {
+		samples.ACCLTimes[i], samples.ACCL[i] = a.Time, a.Value.(ACCL)
+	}
+	for i, g := range s.GYRO {
+		samples.GYROTimes[i], samples.GYRO[i] = g.Time, g.Value.(GYRO)
+	}
+	// own temporary file as same video may be loaded concurrently
+	f, err := os.CreateTemp(st.Dir, hash+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+	if err = gob.NewEncoder(f).Encode(samples); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	return os.Rename(f.Name(), st.samplesFile(hash))
+}
+
+// loadSamples session from saved samples, laps are counted again and
+// metadata read again as sidecar may have changed, samples were saved with
+// camera quirks already applied
+func (st *Store) loadSamples(e *StoreEntry, filename string) (s *Session, err error) {
+	meta, err := ReadMetadata(filename)
+	if err != nil {
+		return
+	}
+	f, err := os.Open(st.samplesFile(e.Hash))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	var samples storedSamples
+	if err = gob.NewDecoder(f).Decode(&samples); err != nil {
+		err = fmt.Errorf("unable to decode samples of %s:%s", e.Hash, err)
+		return
+	}
+	if samples.Version != SAMPLES_VERSION {
+		err = fmt.Errorf("samples of %s are version %d", e.Hash, samples.Version)
+		return
+	}
+	s = &Session{
+		Filename: filename,
+		GPS:      make([]Timely, len(samples.GPS)),
+		ACCL:     make([]Timely, len(samples.ACCL)),
+		GYRO:     make([]Timely, len(samples.GYRO)),
+		Meta:     meta,
+		Quirks:   QuirksFor(meta.Camera),
+	}
+	s.Quirks.IMU = samples.IMU
+	for i := range samples.GPS {
+		s.GPS[i] = Timely{Time: samples.GPSTimes[i], Value: samples.GPS[i]}
+	}
+	for i := range samples.ACCL {
+		s.ACCL[i] = Timely{Time: samples.ACCLTimes[i], Value: samples.ACCL[i]}
+	}
+	for i := range samples.GYRO {
+		s.GYRO[i] = Timely{Time: samples.GYROTimes[i], Value: samples.GYRO[i]}
+	}
+	// same track as first load when still known
+	if track := TheWorld.TrackByName(e.Track); track != nil && len(s.GPS) > 1 {
+		s.CountLaps(track)
+		return
+	}
+	err = s.countLaps()
+	return
+}
+
+// Load session of filename from store, telemetry is read and stored on first load,
+// Telemetry field is nil for sessions read from store
+func (st *Store) Load(filename string) (s *Session, err error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return
+	}
+	hash, err := FileHash(filename)
+	if err != nil {
+		return
+	}
+	st.mutex.Lock()
+	e, ok := st.index[hash]
+	st.mutex.Unlock()
+	if ok {
+		if s, err = st.loadSamples(e, filename); err == nil || errors.Is(err, ErrUnknownTrack) {
+			if merr := st.updateMeta(hash, s.Meta); merr != nil {
+				err = merr
+			}
+			return
+		}
+		// broken cache, read video again
+	}
+	if s, err = LoadSession(filename); err != nil && !errors.Is(err, ErrUnknownTrack) {
+		return
+	}
+	loadErr := err
+	if err = st.add(hash, info.Size(), s); err != nil {
+		return
+	}
+	return s, loadErr
+}
+
+// add loaded session to store, driver is kept when session is replaced
+// and sidecar gives none
+func (st *Store) add(hash string, size int64, s *Session) (err error) {
+	if err = st.saveSamples(hash, s); err != nil {
+		return
+	}
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	e := newStoreEntry(hash, size, s)
+	if previous, ok := st.index[hash]; ok && e.Driver == "" {
+		e.Driver = previous.Driver
+	}
+	st.index[hash] = e
+	return st.saveIndex()
+}
+
+// updateMeta metadata of a stored session, driver of sidecar replaces the one
+// set with SetDriver, index is only written when something changed
+func (st *Store) updateMeta(hash string, meta Metadata) (err error) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	e, ok := st.index[hash]
+	if !ok {
+		return
+	}
+	changed := !reflect.DeepEqual(e.Meta, meta)
+	if meta.Driver != "" && meta.Driver != e.Driver {
+		e.Driver = meta.Driver
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	e.Meta = meta
+	return st.saveIndex()
+}
+
+// SetDriver name of driver of a stored session
+func (st *Store) SetDriver(hash, driver string) (err error) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	e, ok := st.index[hash]
+	if !ok {
+		return fmt.Errorf("unknown session %s", hash)
+	}
+	e.Driver = driver
+	return st.saveIndex()
+}
+
+// Remove session from store
+func (st *Store) Remove(hash string) (err error) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	if _, ok := st.index[hash]; !ok {
+		return fmt.Errorf("unknown session %s", hash)
+	}
+	delete(st.index, hash)
+	if err = os.Remove(st.samplesFile(hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	return st.saveIndex()
+}
+
+// LapQuery filter of stored laps, empty fields match everything
+type LapQuery struct {
+	Track  string
+	Driver string
+	From   time.Time
+	To     time.Time
+}
+
+// match lap of session e
+func (q LapQuery) match(e *StoreEntry, l LapRecord) bool {
+	if q.Track != "" && q.Track != e.Track {
+		return false
+	}
+	if q.Driver != "" && q.Driver != e.Driver {
+		return false
+	}
+	if !q.From.IsZero() && l.Start.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !l.Start.Before(q.To) {
+		return false
+	}
+	return true
+}
+
+// Laps all stored laps matching q, sorted by start
+func (st *Store) Laps(q LapQuery) (laps []LapRecord) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	laps = make([]LapRecord, 0)
+	for _, e := range st.entries() {
+		for _, l := range e.Laps {
+			if q.match(e, l) {
+				l.Driver = e.Driver
+				laps = append(laps, l)
+			}
+		}
+	}
+	return
+}
+
+// BestLaps best lap per track and driver among laps matching q,
+// sorted by track and lap time
+func (st *Store) BestLaps(q LapQuery) (best []LapRecord) {
+	type key struct{ track, driver string }
+	bests := make(map[key]LapRecord)
+	for _, l := range st.Laps(q) {
+		k := key{l.Track, l.Driver}
+		if b, ok := bests[k]; !ok || l.Time < b.Time {
+			bests[k] = l
+		}
+	}
+	best = make([]LapRecord, 0, len(bests))
+	for _, l := range bests {
+		best = append(best, l)
+	}
+	sort.Slice(best, func(i, j int) bool {
+		if best[i].Track != best[j].Track {
+			return best[i].Track < best[j].Track
+		}
+		return best[i].Time < best[j].Time
+	})
+	return
+}
+
+// History best lap of each session matching q, sorted by time, to follow progress
+func (st *Store) History(q LapQuery) (history []LapRecord) {
+	history = make([]LapRecord, 0)
+	byHash := make(map[string]int)
+	for _, l := range st.Laps(q) {
+		i, ok := byHash[l.Hash]
+		if !ok {
+			byHash[l.Hash] = len(history)
+			history = append(history, l)
+			continue
+		}
+		if l.Time < history[i].Time {
+			history[i] = l
+		}
+	}
+	return
+}
@@ -0,0 +1,88 @@
+package gokart
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	st, err := OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := circleSession([]float64{10, 12, 11})
+	second := circleSession([]float64{13, 12})
+	// one week later
+	for i := range second.GPS {
+		second.GPS[i].Time = second.GPS[i].Time.Add(7 * 24 * time.Hour)
+	}
+	second.CountLaps(second.Track)
+	for hash, s := range map[string]*Session{"first": first, "second": second} {
+		if err = st.add(hash, 0, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = st.SetDriver("second", "Ayrton"); err != nil {
+		t.Fatal(err)
+	}
+	// index is saved
+	if st, err = OpenStore(dir); err != nil {
+		t.Fatal(err)
+	}
+	if laps := st.Laps(LapQuery{}); len(laps) != 3 {
+		t.Errorf("found %d laps should be 3", len(laps))
+	}
+	best := st.BestLaps(LapQuery{Track: "Circle"})
+	if len(best) != 2 || best[0].Driver != "Ayrton" || best[0].Hash != "second" {
+		t.Errorf("wrong best laps %+v", best)
+	}
+	history := st.History(LapQuery{})
+	if len(history) != 2 || history[0].Hash != "first" || history[0].Lap != 2 {
+		t.Errorf("wrong history %+v", history)
+	}
+	entries := st.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("found %d entries should be 2", len(entries))
+	}
+	s, err := st.loadSamples(&entries[0], "first.mp4")
+	if err != nil && err != ErrUnknownTrack {
+		t.Fatal(err)
+	}
+	if len(s.GPS) != len(first.GPS) || !s.GPS[10].Time.Equal(first.GPS[10].Time) || s.GPS[10].Value != first.GPS[10].Value {
+		t.Errorf("samples not restored")
+	}
+}
+
+func TestStoreConcurrentSave(t *testing.T) {
+	st, err := OpenStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := circleSession([]float64{10, 12})
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- st.saveSamples("same", s)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(st.Dir, "*"))
+	if len(files) != 1 || filepath.Base(files[0]) != "same.gob" {
+		t.Errorf("files left in store %v", files)
+	}
+	if _, err = st.loadSamples(&StoreEntry{Hash: "same", Track: "Circle"}, "same.mp4"); err != nil && err != ErrUnknownTrack {
+		t.Fatal(err)
+	}
+}
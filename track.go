@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	_ "image/png"
+	"io"
 	"log"
 	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 )
@@ -24,6 +27,8 @@ var (
 	green  = color.RGBA{0, 255, 0, 255}
 	blue   = color.RGBA{0, 0, 255, 255}
 	orange = color.RGBA{255, 180, 0, 255}
+	purple = color.RGBA{170, 0, 255, 255}
+	grey   = color.RGBA{128, 128, 128, 255}
 )
 
 // Track informations like start lines sectors...
@@ -36,6 +41,9 @@ type Track struct {
 	Start    Line        `json:"start"`
 	Sectors  []Line      `json:"sectors"`
 	Limits   Line        `json:"limits"`
+	// Boundaries asphalt edges, optional, used for track limits
+	Boundaries *Boundaries `json:"boundaries,omitempty"`
+	Corners    []Corner    `json:"corners,omitempty"`
 }
 
 // SetLimits, update track bounding box
@@ -56,6 +64,11 @@ func (t Track) NewLapStart(g1, g2 Timely) (start time.Time) {
 	return Crossed(t.Start, g1, g2)
 }
 
+// CrossStart start line crossing with its error estimate
+func (t Track) CrossStart(g1, g2 Timely) (c Crossing, ok bool) {
+	return CrossLine(t.Start, g1, g2)
+}
+
 // ShortName track name usable by OS (filename...)
 func (t Track) ShortName() string {
 	return strings.ReplaceAll(t.Name, " ", "")
@@ -66,93 +79,70 @@ func (t Track) ImageFileName(path string) string {
 	return filepath.Join(path, fmt.Sprintf("%s.png", t.ShortName()))
 }
 
-// UpdateMap read given image will be used as aerial image
-func (t *Track) UpdateMap(path string) (err error) {
-	if t.Map != nil {
-		// map already loaded
-	}
-	var imgFile *os.File
-	if imgFile, err = os.Open(t.ImageFileName(path)); err != nil {
+// loadMapImage read aerial image as RGBA
+func loadMapImage(filename string) (rgba *image.RGBA, err error) {
+	imgFile, err := os.Open(filename)
+	if err != nil {
 		return
 	}
-	// iwidth x iheight files to close
 	defer imgFile.Close()
-	var (
-		img any
-		ok  bool
-	)
-	if img, _, err = image.Decode(imgFile); err != nil {
+	img, _, err := image.Decode(imgFile)
+	if err != nil {
+		err = fmt.Errorf("unable to decode %s:%s", filename, err)
 		return
 	}
-	if t.Map, ok = img.(*image.RGBA); !ok {
-		err = fmt.Errorf("map image is not *image.RGBA but %T", img)
+	var ok bool
+	if rgba, ok = img.(*image.RGBA); !ok {
+		rgba = image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	}
+	return
+}
+
+// UpdateMap read given image will be used as aerial image, only once.
+// It modifies the track, use a MapCache or a Renderer for tracks shared by goroutines
+func (t *Track) UpdateMap(path string) (err error) {
+	if t.Map != nil {
+		// map already loaded
+		return
 	}
+	t.Map, err = loadMapImage(t.ImageFileName(path))
 	return
 }
 
-// PosToXY given map boundaries and lat lon return x y int map
+// PosToXY given map boundaries and lat lon return x y int map,
+// position is measured in metres from south west corner of limits
 func (t Track) PosToXY(r image.Rectangle, lat, lon float64) (x, y int) {
-	xratio := (lon - t.Limits.P1.Longitude) / (t.Limits.P2.Longitude - t.Limits.P1.Longitude)
-	yratio := 1.0 - ((lat - t.Limits.P1.Latitude) / (t.Limits.P2.Latitude - t.Limits.P1.Latitude))
+	p := NewProjection(t.Limits.P1)
+	corner := p.Point(t.Limits.P2)
+	pos := p.Point(NewGPS5(lat, lon))
+	xratio := pos.E / corner.E
+	yratio := 1.0 - pos.N/corner.N
 	x = int(float64(r.Max.X-r.Min.X-TILE_SIZE)*xratio+0.5) + (TILE_SIZE / 2) + 64
 	y = int(float64(r.Max.Y-r.Min.Y-TILE_SIZE)*yratio+0.5) + (TILE_SIZE / 2) + 64
 	return
 }
 
-// Crossed do we cross line and when
+// Crossed do we cross line and when, zero time when line is not crossed
 func Crossed(line Line, g1, g2 Timely) (t time.Time) {
-	g1Side := line.To(g1.Value.(GPS5))
-	g2Side := line.To(g2.Value.(GPS5))
-	if g1Side*g2Side > 0. {
-		// we don't cross line
-		return
+	if c, ok := CrossLine(line, g1, g2); ok {
+		t = c.Time
 	}
-	if math.Abs(g1Side) > 8.0 || math.Abs(g2Side) > 8.0 {
-		// too far (> 8 m) from line
-		return
-	}
-	from1 := math.Abs(g1Side) / (math.Abs(g1Side) + math.Abs(g2Side))
-	frominNano := float64(g2.Time.Sub(g1.Time)) * from1
-	t = g1.Time.Add(time.Duration(frominNano + 0.5))
 	return
 }
 
-// haversin(θ) function
-func hsin(theta float64) float64 {
-	return math.Pow(math.Sin(theta/2), 2)
-}
-
-// Distance function returns the distance (in meters) between two points of
-//
-//	a given longitude and latitude relatively accurately (using a spherical
-//	approximation of the Earth) through the Haversin Distance Formula for
-//	great arc distance on a sphere with accuracy for small distances
-//
-// point coordinates are supplied in degrees and converted into rad. in the func
-//
-// distance returned is METERS!!!!!!
-// http://en.wikipedia.org/wiki/Haversine_formula
+// Distance horizontal distance in metres between two points, in the WGS84
+// tangent plane of g1, precise for the few kilometres of a track
 func Distance(g1, g2 GPS5) float64 {
-	// convert to radians
-	// must cast radius as float to multiply later
-	var la1, lo1, la2, lo2, r float64
-	la1 = g1.Latitude * math.Pi / 180
-	lo1 = g1.Longitude * math.Pi / 180
-	la2 = g2.Latitude * math.Pi / 180
-	lo2 = g2.Longitude * math.Pi / 180
-
-	r = 6378100 // Earth radius in METERS
-
-	// calculate
-	h := hsin(la2-la1) + math.Cos(la1)*math.Cos(la2)*hsin(lo2-lo1)
-
-	return 2 * r * math.Asin(math.Sqrt(h))
+	return NewProjection(g1).Point(g2).Norm()
 }
 
 // LapCounter keep all trak lap
 type LapCounter struct {
-	track       *Track
-	laps        [][]time.Time
+	track *Track
+	laps  [][]time.Time
+	// startErrors error estimate of start time of each lap
+	startErrors []time.Duration
 	best        int
 	bestD       time.Duration
 	bestSectors []time.Duration
@@ -160,6 +150,10 @@ type LapCounter struct {
 	status      []int
 
 	prevstatus []int
+	// statuses sector status of each finished lap, as shown live
+	statuses [][]int
+	// out where finished laps are printed, nil for silent
+	out io.Writer
 }
 
 // NewLapCounter create a LapCounter from given track
@@ -173,9 +167,15 @@ func NewLapCounter(track *Track) (l LapCounter) {
 	l.bestSectors = make([]time.Duration, len(l.track.Sectors)+1)
 	l.status = make([]int, len(l.track.Sectors)+1)
 	l.prevstatus = make([]int, len(l.track.Sectors)+1)
+	l.out = os.Stdout
 	return
 }
 
+// SetOutput where finished laps are printed, nil for silent
+func (l *LapCounter) SetOutput(w io.Writer) {
+	l.out = w
+}
+
 // Track associated track
 func (l LapCounter) Track() *Track {
 	return l.track
@@ -186,6 +186,20 @@ func (l LapCounter) SectorStatus() []int {
 	return l.status
 }
 
+// SectorColor usual chrono color for a sector status
+// purple best, green improved, red slower, grey unknown
+func SectorColor(status int) color.RGBA {
+	switch status {
+	case 2:
+		return purple
+	case 1:
+		return green
+	case -1:
+		return red
+	}
+	return grey
+}
+
 // PrevStatus status of previsou sectors
 func (l LapCounter) PrevStatus() []int {
 	return l.prevstatus
@@ -209,12 +223,14 @@ func (l *LapCounter) TheroreticalBest() (best time.Duration) {
 // appendEmptyLap, sector times
 func (l *LapCounter) appendEmptyLap() {
 	l.laps = append(l.laps, make([]time.Time, 1+len(l.track.Sectors)))
+	l.startErrors = append(l.startErrors, 0)
 }
 
 // Update lapcounter with information at t
 func (l *LapCounter) Update(t time.Time, prev, current Timely) {
 	// New lap ?
-	if newStart := l.track.NewLapStart(prev, current); !newStart.IsZero() {
+	if crossing, ok := l.track.CrossStart(prev, current); ok {
+		newStart := crossing.Time
 		var lapD time.Duration
 		if !l.laps[l.current][0].IsZero() {
 			// one more full lap
@@ -231,12 +247,16 @@ func (l *LapCounter) Update(t time.Time, prev, current Timely) {
 		l.appendEmptyLap()
 		l.current++
 		l.laps[l.current][0] = newStart
+		l.startErrors[l.current] = crossing.Error
+		l.statuses = append(l.statuses, slices.Clone(l.status))
 		// cleanup status
 		for i := range l.status {
 			l.prevstatus[i] = l.status[i]
 			l.status[i] = 0
 		}
-		l.PrintLap(l.current - 1)
+		if l.out != nil {
+			l.PrintLap(l.current - 1)
+		}
 	}
 	// look at sectors
 	for i, sector := range l.track.Sectors {
@@ -263,8 +283,13 @@ func (l *LapCounter) updateSector(i int, d time.Duration, nextStart time.Time) {
 			if !l.laps[l.best][i].IsZero() {
 				var before time.Duration
 				if i+1 == len(l.laps[l.best]) {
-					// last sector, use next start
-					before = nextStart.Sub(l.laps[l.best][i])
+					// last sector ends with start of lap after best one,
+					// not known yet when current lap is the new best
+					end := nextStart
+					if l.best+1 < len(l.laps) {
+						end = l.laps[l.best+1][0]
+					}
+					before = end.Sub(l.laps[l.best][i])
 				} else {
 					if !l.laps[l.best][i+1].IsZero() {
 						// something to compare
@@ -314,7 +339,11 @@ func (l LapCounter) PrintLap(lap int) {
 		}
 		fmt.Fprintf(&b, " S%02d %s", last, DurationToChrono(d))
 	}
-	fmt.Println(b.String())
+	out := l.out
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintln(out, b.String())
 }
 
 func (l LapCounter) Current() int {
@@ -343,10 +372,81 @@ func (l LapCounter) BestTime() (d time.Duration) {
 	return
 }
 
+// CompleteLaps index of all laps with a start and a stop
+func (l LapCounter) CompleteLaps() (laps []int) {
+	laps = make([]int, 0)
+	for i := 0; i+1 < len(l.laps); i++ {
+		if !l.laps[i][0].IsZero() {
+			laps = append(laps, i)
+		}
+	}
+	return
+}
+
+// LapStatus sector status of a finished lap as it was at end of lap,
+// nil if lap is not finished
+func (l LapCounter) LapStatus(lap int) []int {
+	if lap < 0 || lap >= len(l.statuses) {
+		return nil
+	}
+	return l.statuses[lap]
+}
+
+// LapStart time when lap started, zero if unknown
+func (l LapCounter) LapStart(lap int) (t time.Time) {
+	if lap < 0 || lap >= len(l.laps) {
+		return
+	}
+	return l.laps[lap][0]
+}
+
+// LapTime duration of given lap, 0 if not complete
+func (l LapCounter) LapTime(lap int) (d time.Duration) {
+	if lap < 0 || lap+1 >= len(l.laps) || l.laps[lap][0].IsZero() {
+		return
+	}
+	return l.laps[lap+1][0].Sub(l.laps[lap][0])
+}
+
+// LapError error estimate of LapTime, sum of errors of both start line crossings
+func (l LapCounter) LapError(lap int) (d time.Duration) {
+	if l.LapTime(lap) == 0 {
+		return
+	}
+	return l.startErrors[lap] + l.startErrors[lap+1]
+}
+
+// SectorTimes duration of each sector of given lap, 0 when missing
+func (l LapCounter) SectorTimes(lap int) (times []time.Duration) {
+	times = make([]time.Duration, len(l.track.Sectors)+1)
+	if lap < 0 || lap >= len(l.laps) {
+		return
+	}
+	for i := range times {
+		start := l.laps[lap][i]
+		var stop time.Time
+		if i+1 < len(l.laps[lap]) {
+			stop = l.laps[lap][i+1]
+		} else if lap+1 < len(l.laps) {
+			// last sector ends with next lap
+			stop = l.laps[lap+1][0]
+		}
+		if !start.IsZero() && !stop.IsZero() {
+			times[i] = stop.Sub(start)
+		}
+	}
+	return
+}
+
 func GetSpeed(gps []Timely, index int) (value float64) {
 	return gps[index].Value.(GPS5).Speed3D
 }
 
+// GetAccuracy GPS accuracy in cm
+func GetAccuracy(gps []Timely, index int) (value float64) {
+	return float64(gps[index].Value.(GPS5).Accuracy)
+}
+
 // ACC_DELTA how many step in past and future to compute acceleration
 const ACC_DELTA = 12
 
@@ -363,6 +463,40 @@ func GetAcc(gps []Timely, index int) (value float64) {
 	return (gps[istop].Value.(GPS5).Speed3D - gps[istart].Value.(GPS5).Speed3D) / gps[istop].Time.Sub(gps[istart].Time).Seconds()
 }
 
+// LAT_DELTA how many step in past and future to compute heading change
+const LAT_DELTA = 3
+
+// STANDARD_GRAVITY in m/s²
+const STANDARD_GRAVITY = 9.80665
+
+// heading in radians from g1 to g2, 0 is north, clockwise
+func heading(g1, g2 GPS5) float64 {
+	return Heading(Point{}, NewProjection(g1).Point(g2))
+}
+
+// GetLateralAcc speed × yaw rate in m/s², positive when turning right
+func GetLateralAcc(gps []Timely, index int) (value float64) {
+	istart := max(0, index-LAT_DELTA)
+	istop := min(len(gps)-1, index+LAT_DELTA)
+	if istop-istart < 2 {
+		return
+	}
+	mid := (istart + istop) / 2
+	g1, g2, g3 := gps[istart].Value.(GPS5), gps[mid].Value.(GPS5), gps[istop].Value.(GPS5)
+	if g1 == g2 || g2 == g3 {
+		// not moving
+		return
+	}
+	turn := heading(g2, g3) - heading(g1, g2)
+	// back to [-π π]
+	turn = math.Remainder(turn, 2*math.Pi)
+	dt := gps[istop].Time.Sub(gps[istart].Time).Seconds() / 2
+	if dt <= 0 {
+		return
+	}
+	return gps[index].Value.(GPS5).Speed3D * turn / dt
+}
+
 func GetAccColor(acc, min, max float64) (color color.RGBA) {
 	color.A = 255
 	if acc < 0.0 {
@@ -379,70 +513,48 @@ func GetAccColor(acc, min, max float64) (color color.RGBA) {
 	return
 }
 
+// DrawLap draw lap trajectory on track aerial image colored by a registered mode
 func (l LapCounter) DrawLap(path, mode string, gps []Timely, index int) (rgba image.Image, err error) {
-	getValue := GetSpeed
-	switch mode {
-	case "acc":
-		getValue = GetAcc
-	}
-	gpsStart := FindIndex(l.laps[index][0], gps)
-	gpsStop := FindIndex(l.laps[index+1][0], gps)
-	log.Println("GPS start", gpsStart, gps[gpsStart])
-	log.Println("GPS stop", gpsStop, gps[gpsStop])
-	minMode := 300000000.0
-	maxMode := 0.0
-	for i := gpsStart; i <= gpsStop; i++ {
-		if getValue(gps, i) > maxMode {
-			maxMode = getValue(gps, i)
-		}
-		if getValue(gps, i) < minMode {
-			minMode = getValue(gps, i)
-		}
+	m, ok := GetMode(mode)
+	if !ok {
+		err = fmt.Errorf("unknown mode %s, available: %s", mode, strings.Join(Modes(), ", "))
+		return
 	}
-	log.Println(mode, "between", minMode, "and", maxMode)
-	// ensure map loaded
-	if err = l.track.UpdateMap(path); err != nil {
+	return l.DrawLapMode(path, m, gps, index)
+}
+
+// DrawLapMode draw lap trajectory on a new copy of track aerial image colored by given mode
+func (l LapCounter) DrawLapMode(path string, mode Mode, gps []Timely, index int) (rgba image.Image, err error) {
+	r, err := NewRenderer(l.track, path)
+	if err != nil {
 		return
 	}
-	// draw all gps lines
-	rgba = l.track.Map
-	r := rgba.Bounds()
-	for i := gpsStart; i < gpsStop; i++ {
-		x1, y1 := l.track.PosToXY(r, gps[i].Value.(GPS5).Latitude, gps[i].Value.(GPS5).Longitude)
-		x2, y2 := l.track.PosToXY(r, gps[i+1].Value.(GPS5).Latitude, gps[i+1].Value.(GPS5).Longitude)
-		color := white
-		switch mode {
-		case "speed", "res":
-			ratio := (getValue(gps, i) - minMode) / (maxMode - minMode)
-			color = green
-			if ratio < 0.5 {
-				color.R = uint8((2*ratio)*float64(green.R) + (1-2*ratio)*float64(blue.R))
-				color.G = uint8((2*ratio)*float64(green.G) + (1-2*ratio)*float64(blue.G))
-				color.B = uint8((2*ratio)*float64(green.B) + (1-2*ratio)*float64(blue.B))
-			}
-			if ratio > 0.5 {
-				ratio -= 0.5
-				color.R = uint8((2*ratio)*float64(red.R) + (1-2*ratio)*float64(green.R))
-				color.G = uint8((2*ratio)*float64(red.G) + (1-2*ratio)*float64(green.G))
-				color.B = uint8((2*ratio)*float64(red.B) + (1-2*ratio)*float64(green.B))
-			}
-			if mode == "res" {
-				// accuracy in meters
-				accuracy := float64(gps[i].Value.(GPS5).Accuracy) / 100.
-				// radius, accuracy in pixels
-				// accuracy seems not to be so accurate...
-				radius := 3 * accuracy / MeterPerPixel(gps[i].Value.(GPS5).Latitude)
-				DrawCircle(rgba.(*image.RGBA), x1, y1, int(radius+0.5), color)
-			} else {
-				DrawCircleLine(rgba.(*image.RGBA), x1, y1, x2, y2, 3, color)
-			}
-		case "acc":
-			DrawCircleLine(
-				rgba.(*image.RGBA), x1, y1, x2, y2, 3,
-				GetAccColor(getValue(gps, i), minMode, maxMode))
+	if err = r.DrawLap(l, mode, gps, index); err != nil {
+		return
+	}
+	return r.Canvas, nil
+}
+
+// drawLapLabels start and sector lines with sector times and lap chrono
+func (l LapCounter) drawLapLabels(img *image.RGBA, lap int) {
+	r := img.Bounds()
+	size := max(16, r.Dy()/60)
+	times := l.SectorTimes(lap)
+	// each sector ends on next sector line, last one on start line
+	ends := append(slices.Clone(l.track.Sectors), l.track.Start)
+	for i, end := range ends {
+		x1, y1 := l.track.PosToXY(r, end.P1.Latitude, end.P1.Longitude)
+		x2, y2 := l.track.PosToXY(r, end.P2.Latitude, end.P2.Longitude)
+		DrawPolyline(img, []Vertex{VertexOf(x1, y1), VertexOf(x2, y2)}, Stroke{Width: 4, Color: white, Cap: CapButt})
+		if i < len(times) && times[i] > 0 {
+			DrawLabel(img, (x1+x2)/2, min(y1, y2)-size/2,
+				fmt.Sprintf("S%d %s", i+1, DurationToChrono(times[i])),
+				size, white, WithAlpha(color.RGBA{0, 0, 0, 255}, 0.7), BottomCenter)
 		}
 	}
-	return
+	DrawLabel(img, r.Min.X+size, r.Min.Y+size,
+		fmt.Sprintf("Lap %02d %s", lap, DurationToChrono(l.LapTime(lap))),
+		2*size, white, WithAlpha(color.RGBA{0, 0, 0, 255}, 0.7), TopLeft)
 }
 
 // MeterPerPixel for zoom = 20
@@ -0,0 +1,15 @@
+package gokart
+
+import "testing"
+
+func TestLastSectorStatus(t *testing.T) {
+	// fast first lap is best, second lap is slower everywhere
+	s := circleSession([]float64{12, 10, 10})
+	if s.Laps.Best() != 1 {
+		t.Fatalf("best lap is %d", s.Laps.Best())
+	}
+	// last sector of best lap ends with start of lap 2, not of lap 3
+	if status := s.Laps.LapStatus(2); len(status) != 3 || status[2] != -1 {
+		t.Errorf("slower last sector status is %v", status)
+	}
+}
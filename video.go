@@ -0,0 +1,227 @@
+package gokart
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// ErrStopFrames returned by a FrameFunc to stop decoding without error
+var ErrStopFrames = errors.New("stop decoding frames")
+
+// FrameFunc called for each decoded frame, n is counted from decoding start
+// and img is reused for next frame
+type FrameFunc func(n int, img *image.RGBA) error
+
+// DecodeFrames decode frames of filename as RGBA, from start during duration
+// (until the end when duration is 0)
+func DecodeFrames(filename string, info VideoInfo, start, duration time.Duration, fn FrameFunc) (err error) {
+	in := ffmpeg.KwArgs{}
+	out := ffmpeg.KwArgs{"format": "rawvideo", "pix_fmt": "rgba"}
+	if start > 0 {
+		in["ss"] = fmt.Sprintf("%.3f", start.Seconds())
+	}
+	if duration > 0 {
+		out["t"] = fmt.Sprintf("%.3f", duration.Seconds())
+	}
+	dr, dw := io.Pipe()
+	decoded := make(chan error, 1)
+	go func() {
+		derr := ffmpeg.Input(filename, in).Output("pipe:", out).WithOutput(dw).Run()
+		dw.CloseWithError(derr)
+		decoded <- derr
+	}()
+	img := image.NewRGBA(image.Rect(0, 0, info.Width, info.Height))
+	stopped := false
+	for n := 0; ; n++ {
+		if _, err = io.ReadFull(dr, img.Pix); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			break
+		}
+		if err = fn(n, img); err != nil {
+			if err == ErrStopFrames {
+				err = nil
+				stopped = true
+			}
+			break
+		}
+	}
+	// stop ffmpeg if still running
+	dr.Close()
+	if derr := <-decoded; err == nil && !stopped && derr != nil {
+		err = fmt.Errorf("decoding %s:%s", filename, derr)
+	}
+	return
+}
+
+// FRAME_SEEK_GAP wanted frames further apart are reached by seeking instead of
+// decoding all frames between them
+const FRAME_SEEK_GAP = 2 * time.Second
+
+// StrideFrames frame numbers from first to last (included) every stride frames
+func StrideFrames(first, last, stride int) (frames []int) {
+	for n := first; n <= last; n += max(1, stride) {
+		frames = append(frames, n)
+	}
+	return
+}
+
+// frameGroups sorted unique frames split where they are more than gap apart
+func frameGroups(frames []int, gap int) (groups [][]int) {
+	sorted := slices.Clone(frames)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+	for i, n := range sorted {
+		if i == 0 || n-sorted[i-1] > gap {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], n)
+	}
+	return
+}
+
+// ExtractFrames decode only given frames of filename, frames are numbered from 1
+// like FrameAt+1. Distant frames are reached by seeking to the keyframe before
+// them and decoding forward. fn gets frame number and its own copy of image,
+// it is called from workers goroutines at the same time and in any order.
+func ExtractFrames(filename string, info VideoInfo, frames []int, workers int, fn FrameFunc) (err error) {
+	if info.RateNum == 0 {
+		return fmt.Errorf("unknown frame rate of %s", filename)
+	}
+	type frame struct {
+		n   int
+		img *image.RGBA
+	}
+	todo := make(chan frame, workers)
+	errs := make(chan error, max(1, workers))
+	var wg sync.WaitGroup
+	for range max(1, workers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range todo {
+				if werr := fn(f.n, f.img); werr != nil {
+					errs <- werr
+					// drain so that decoding is not blocked
+					for range todo {
+					}
+					return
+				}
+			}
+		}()
+	}
+	failed := func() (ferr error) {
+		select {
+		case ferr = <-errs:
+		default:
+		}
+		return
+	}
+	gap := info.FrameAt(FRAME_SEEK_GAP)
+	half := info.FrameTime(1) / 2
+	for _, group := range frameGroups(frames, gap) {
+		first, last := group[0], group[len(group)-1]
+		wanted := make(map[int]bool, len(group))
+		for _, n := range group {
+			wanted[n] = true
+		}
+		// half a frame before so that rounding of seek time does not skip first frame
+		start := max(0, info.FrameTime(first-1)-half)
+		err = DecodeFrames(filename, info, start, info.FrameTime(last-first+1), func(n int, img *image.RGBA) error {
+			count := first + n
+			if count > last {
+				return ErrStopFrames
+			}
+			if ferr := failed(); ferr != nil {
+				return ferr
+			}
+			if wanted[count] {
+				copied := image.NewRGBA(img.Rect)
+				copy(copied.Pix, img.Pix)
+				todo <- frame{count, copied}
+			}
+			return nil
+		})
+		if err != nil {
+			break
+		}
+	}
+	close(todo)
+	wg.Wait()
+	if ferr := failed(); err == nil {
+		err = ferr
+	}
+	return
+}
+
+// FrameEvent moment of a session worth a frame
+type FrameEvent struct {
+	// Kind "start" of lap, "sector" line crossing or corner "apex"
+	Kind string    `json:"kind"`
+	Lap  int       `json:"lap"`
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+}
+
+// FRAME_EVENTS kinds of FrameEvents
+var FRAME_EVENTS = []string{"start", "sector", "apex"}
+
+// FrameEvents events of given kinds, sorted by time. Apex is the slowest point
+// at the end of each braking zone, named after closest track corner.
+func (s *Session) FrameEvents(kinds ...string) (events []FrameEvent, err error) {
+	if s.Track == nil {
+		return nil, ErrUnknownTrack
+	}
+	proj := s.Track.Start.Projection()
+	for _, kind := range kinds {
+		switch kind {
+		case "start", "sector":
+			for lap := range s.Laps.laps {
+				for i, t := range s.Laps.laps[lap] {
+					if t.IsZero() || (kind == "start") != (i == 0) {
+						continue
+					}
+					events = append(events, FrameEvent{Kind: kind, Lap: lap, Name: fmt.Sprintf("S%d", i+1), Time: t})
+				}
+			}
+		case "apex":
+			for _, lap := range s.Laps.CompleteLaps() {
+				zones, zerr := s.Laps.Zones(s.GPS, s.ACCL, lap, DefaultZoneConfig)
+				if zerr != nil {
+					return nil, zerr
+				}
+				for _, z := range zones {
+					if z.Kind == Braking {
+						events = append(events, FrameEvent{
+							Kind: kind,
+							Lap:  lap,
+							Name: s.Track.closestCorner(proj, z.Stop.Value.(GPS5)),
+							Time: z.Stop.Time,
+						})
+					}
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown event %s, available: %s", kind, strings.Join(FRAME_EVENTS, ", "))
+		}
+	}
+	slices.SortStableFunc(events, func(a, b FrameEvent) int {
+		return a.Time.Compare(b.Time)
+	})
+	return
+}
+
+// FrameOf frame number showing t, video starts at first GPS sample like other
+// frame tools, first frame is 1
+func (s *Session) FrameOf(info VideoInfo, t time.Time) int {
+	return info.FrameAt(t.Sub(s.GPS[0].Time)) + 1
+}
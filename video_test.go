@@ -0,0 +1,46 @@
+package gokart
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFrameGroups(t *testing.T) {
+	if frames := StrideFrames(10, 20, 5); !slices.Equal(frames, []int{10, 15, 20}) {
+		t.Errorf("stride frames are %v", frames)
+	}
+	groups := frameGroups([]int{500, 3, 1, 3, 60, 2000, 1990}, 60)
+	expected := [][]int{{1, 3, 60}, {500}, {1990, 2000}}
+	if !slices.EqualFunc(groups, expected, slices.Equal) {
+		t.Errorf("groups are %v should be %v", groups, expected)
+	}
+}
+
+func TestFrameEvents(t *testing.T) {
+	s := circleSession([]float64{10, 12, 11})
+	events, err := s.FrameEvents("start", "sector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	starts := 0
+	for i, e := range events {
+		if i > 0 && e.Time.Before(events[i-1].Time) {
+			t.Error("events should be sorted by time")
+		}
+		if e.Kind == "start" {
+			starts++
+		}
+	}
+	// one start and two sectors per lap
+	if starts != 3 || len(events) != 9 {
+		t.Errorf("found %d starts in %d events", starts, len(events))
+	}
+	info := VideoInfo{RateNum: 30, RateDen: 1}
+	// just after 0.1 radian at 10m/s on a 100m circle
+	if n := s.FrameOf(info, s.Laps.LapStart(1)); n != 31 {
+		t.Errorf("lap 1 starts at frame %d should be 31", n)
+	}
+	if _, err = s.FrameEvents("unknown"); err == nil {
+		t.Error("unknown event should fail")
+	}
+}
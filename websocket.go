@@ -0,0 +1,112 @@
+package gokart
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// websocketGUID from RFC 6455, appended to client key
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// websocket server side of a connection, only text messages are sent,
+// messages from client are read and dropped
+type websocket struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// upgradeWebSocket answer handshake of r, error is already sent to client
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (ws *websocket, err error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "websocket expected", http.StatusBadRequest)
+		return nil, errors.New("not a websocket request")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("connection can not be hijacked")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err = rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+	return &websocket{conn, rw}, nil
+}
+
+// WriteText send one unmasked text frame
+func (ws *websocket) WriteText(data []byte) (err error) {
+	header := []byte{0x81}
+	switch n := len(data); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n < 1<<16:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if _, err = ws.rw.Write(header); err != nil {
+		return
+	}
+	if _, err = ws.rw.Write(data); err != nil {
+		return
+	}
+	return ws.rw.Flush()
+}
+
+// drain read client frames until close frame or error
+func (ws *websocket) drain() (err error) {
+	header := make([]byte, 2)
+	for {
+		if _, err = io.ReadFull(ws.rw, header); err != nil {
+			return
+		}
+		opcode := header[0] & 0x0f
+		length := uint64(header[1] & 0x7f)
+		switch length {
+		case 126:
+			ext := make([]byte, 2)
+			if _, err = io.ReadFull(ws.rw, ext); err != nil {
+				return
+			}
+			length = uint64(binary.BigEndian.Uint16(ext))
+		case 127:
+			ext := make([]byte, 8)
+			if _, err = io.ReadFull(ws.rw, ext); err != nil {
+				return
+			}
+			length = binary.BigEndian.Uint64(ext)
+		}
+		if header[1]&0x80 != 0 {
+			// mask key
+			length += 4
+		}
+		if _, err = io.CopyN(io.Discard, ws.rw, int64(length)); err != nil {
+			return
+		}
+		if opcode == 0x8 {
+			return io.EOF
+		}
+	}
+}
+
+// Close connection without closing handshake
+func (ws *websocket) Close() error {
+	return ws.conn.Close()
+}
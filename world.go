@@ -3,8 +3,10 @@ package gokart
 import (
 	"embed"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
-	"math"
+	"os"
 )
 
 // World, all available tracks
@@ -12,18 +14,24 @@ type World struct {
 	Tracks []*Track `json:"tracks"`
 }
 
-// GetTrack from given GPS positions find the closest known track
+// TRACK_DISTANCE metres from start line within which a track is found
+const TRACK_DISTANCE = 300.0
+
+// GetTrack from given GPS positions find the known track with the closest
+// start line, nil when every start line is further than TRACK_DISTANCE
 func (w World) GetTrack(points []Timely) (t *Track) {
-	minD := -1.0
+	minD := TRACK_DISTANCE
 	for _, tr := range w.Tracks {
+		p := tr.Start.Projection()
+		a, b := p.Points(tr.Start)
 		for _, pt := range points {
 			gps := pt.Value.(GPS5)
 			if gps.Accuracy >= 10000 {
 				// not precise enough
 				continue
 			}
-			if minD < 0 || math.Abs(tr.To(gps)) < minD {
-				minD = math.Abs(tr.To(gps))
+			if d, _ := SegmentDistance(p.Point(gps), a, b); d <= minD {
+				minD = d
 				t = tr
 			}
 		}
@@ -31,6 +39,69 @@ func (w World) GetTrack(points []Timely) (t *Track) {
 	return
 }
 
+// LoadWorld read a world json file like data/theworld.json
+func LoadWorld(filename string) (w World, err error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &w); err != nil {
+		err = fmt.Errorf("unable to unmarshal %s:%s", filename, err)
+	}
+	return
+}
+
+// validLine both points are set, different and are valid coordinates
+func validLine(l Line) error {
+	for _, p := range []GPS5{l.P1, l.P2} {
+		if p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180 {
+			return fmt.Errorf("wrong coordinates %f,%f", p.Latitude, p.Longitude)
+		}
+	}
+	if l.P1.Latitude == l.P2.Latitude && l.P1.Longitude == l.P2.Longitude {
+		return errors.New("both points are equal")
+	}
+	if Distance(l.P1, l.P2) > 100 {
+		return fmt.Errorf("line is too long (%.0fm)", Distance(l.P1, l.P2))
+	}
+	return nil
+}
+
+// Validate check track definition, all problems are joined in err
+func (t Track) Validate() (err error) {
+	errs := make([]error, 0)
+	if t.Name == "" {
+		errs = append(errs, errors.New("missing name"))
+	}
+	if verr := validLine(t.Start); verr != nil {
+		errs = append(errs, fmt.Errorf("start:%s", verr))
+	}
+	for i, sector := range t.Sectors {
+		if verr := validLine(sector); verr != nil {
+			errs = append(errs, fmt.Errorf("sector %d:%s", i+1, verr))
+		}
+	}
+	if t.Boundaries != nil {
+		if verr := t.Boundaries.Validate(); verr != nil {
+			errs = append(errs, fmt.Errorf("boundaries:%s", verr))
+		}
+	}
+	if !t.Limits.IsZero() && (t.Limits.P1.Latitude >= t.Limits.P2.Latitude || t.Limits.P1.Longitude >= t.Limits.P2.Longitude) {
+		errs = append(errs, errors.New("limits first point must be south west of second one"))
+	}
+	return errors.Join(errs...)
+}
+
+// TrackByName track with given name, nil if unknown
+func (w World) TrackByName(name string) *Track {
+	for _, t := range w.Tracks {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
 //go:embed data/theworld.json
 var content embed.FS
 
@@ -52,21 +123,20 @@ func init() {
 	}
 }
 
+// ExtractLimits south west and north east corners of GPS positions,
+// computed in metres around first position
 func ExtractLimits(gps []Timely) (limits Line) {
-	for _, p := range gps {
-		v := p.Value.(GPS5)
-		if limits.P1.Latitude == 0. || v.Latitude < limits.P1.Latitude {
-			limits.P1.Latitude = v.Latitude
-		}
-		if limits.P1.Longitude == 0. || v.Longitude < limits.P1.Longitude {
-			limits.P1.Longitude = v.Longitude
-		}
-		if limits.P2.Latitude == 0. || v.Latitude > limits.P2.Latitude {
-			limits.P2.Latitude = v.Latitude
-		}
-		if limits.P2.Longitude == 0. || v.Longitude > limits.P2.Longitude {
-			limits.P2.Longitude = v.Longitude
-		}
+	if len(gps) == 0 {
+		return
+	}
+	p := NewProjection(gps[0].Value.(GPS5))
+	var sw, ne Point
+	for _, g := range gps {
+		pt := p.Point(g.Value.(GPS5))
+		sw = Point{min(sw.E, pt.E), min(sw.N, pt.N)}
+		ne = Point{max(ne.E, pt.E), max(ne.N, pt.N)}
 	}
+	limits.P1 = p.GPS5(sw)
+	limits.P2 = p.GPS5(ne)
 	return
 }
@@ -0,0 +1,19 @@
+package gokart
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetTrack(t *testing.T) {
+	track, gps, _ := circleLaps([]float64{10})
+	w := World{Tracks: []*Track{TheWorld.TrackByName("Ancenis"), track}}
+	if found := w.GetTrack(gps); found != track {
+		t.Errorf("found %v should be circle", found)
+	}
+	// 1km north of circle
+	far := NewGPS5(47.01, 0.2)
+	if found := w.GetTrack([]Timely{{Time: time.Now(), Value: far}}); found != nil {
+		t.Errorf("found %s far from every track", found.Name)
+	}
+}
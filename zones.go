@@ -0,0 +1,369 @@
+package gokart
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"time"
+)
+
+// ZoneKind braking or throttle
+type ZoneKind int
+
+const (
+	// Braking speed is decreasing
+	Braking ZoneKind = iota
+	// Throttle speed is increasing
+	Throttle
+)
+
+func (k ZoneKind) String() string {
+	switch k {
+	case Braking:
+		return "braking"
+	case Throttle:
+		return "throttle"
+	}
+	return fmt.Sprintf("ZoneKind(%d)", int(k))
+}
+
+// Zone a braking or throttle zone inside a lap
+type Zone struct {
+	Kind ZoneKind `json:"kind"`
+	Lap  int      `json:"lap"`
+	// Start and Stop are GPS5 Timely where zone begins and ends
+	Start Timely `json:"start"`
+	Stop  Timely `json:"stop"`
+	// Peak longitudinal acceleration in m/s², negative when braking
+	Peak   float64 `json:"peak"`
+	PeakAt Timely  `json:"peakat"`
+	// SpeedIn and SpeedOut in m/s
+	SpeedIn  float64 `json:"speedin"`
+	SpeedOut float64 `json:"speedout"`
+}
+
+// Duration time spent in zone
+func (z Zone) Duration() time.Duration {
+	return z.Stop.Time.Sub(z.Start.Time)
+}
+
+// SpeedDelta speed lost (braking) or gained (throttle) in m/s, always >= 0
+func (z Zone) SpeedDelta() float64 {
+	if z.Kind == Braking {
+		return z.SpeedIn - z.SpeedOut
+	}
+	return z.SpeedOut - z.SpeedIn
+}
+
+func (z Zone) String() string {
+	return fmt.Sprintf("lap %02d %s at %s for %s, %.0f -> %.0f km/h, peak %.1f m/s²",
+		z.Lap, z.Kind, z.Start.Time.Format("15:04:05.00"), DurationToChrono(z.Duration()),
+		z.SpeedIn*3.6, z.SpeedOut*3.6, z.Peak)
+}
+
+// ZoneConfig thresholds used to detect zones
+type ZoneConfig struct {
+	// BrakeThreshold deceleration (m/s², positive) to enter a braking zone
+	BrakeThreshold float64
+	// ThrottleThreshold acceleration (m/s²) to enter a throttle zone
+	ThrottleThreshold float64
+	// MinDuration shorter zones are ignored
+	MinDuration time.Duration
+	// MinSpeedDelta zones losing/gaining less speed (m/s) are ignored
+	MinSpeedDelta float64
+	// Window moving average half width, in GPS samples
+	Window int
+	// AcclWindow moving average half width, in ACCL samples
+	AcclWindow int
+	// Longitudinal extract forward acceleration from ACCL, depends on how
+	// camera is mounted, when nil forward axis is fitted on GPS speed
+	Longitudinal func(a ACCL) float64
+}
+
+// FORWARD_MIN_R2 forward axis fitted on GPS speed explaining less of
+// GPS acceleration is not used, ACCL may be noisy or out of sync
+const FORWARD_MIN_R2 = 0.5
+
+// DefaultZoneConfig usable for most karts (GPS5 at 18Hz, ACCL at 200Hz)
+var DefaultZoneConfig = ZoneConfig{
+	BrakeThreshold:    2.5,
+	ThrottleThreshold: 1.0,
+	MinDuration:       300 * time.Millisecond,
+	MinSpeedDelta:     1.0,
+	Window:            4,
+	AcclWindow:        20,
+}
+
+// movingAverage centered on each value, window is half width
+func movingAverage(values []float64, window int) (avg []float64) {
+	avg = make([]float64, len(values))
+	if window <= 0 {
+		copy(avg, values)
+		return
+	}
+	sum := 0.0
+	count := 0
+	// sum of [i-window, i+window]
+	for i := 0; i < window && i < len(values); i++ {
+		sum += values[i]
+		count++
+	}
+	for i := range values {
+		if i+window < len(values) {
+			sum += values[i+window]
+			count++
+		}
+		if i-window-1 >= 0 {
+			sum -= values[i-window-1]
+			count--
+		}
+		avg[i] = sum / float64(count)
+	}
+	return
+}
+
+// LongitudinalAcc filtered acceleration (m/s²) from GPS speed between start and stop (included)
+func LongitudinalAcc(gps []Timely, start, stop int, window int) (acc []float64) {
+	speed := make([]float64, stop-start+1)
+	for i := range speed {
+		speed[i] = gps[start+i].Value.(GPS5).Speed3D
+	}
+	speed = movingAverage(speed, window)
+	raw := make([]float64, len(speed))
+	for i := range speed {
+		// central difference
+		prev, next := i-1, i+1
+		if prev < 0 {
+			prev = 0
+		}
+		if next >= len(speed) {
+			next = len(speed) - 1
+		}
+		dt := gps[start+next].Time.Sub(gps[start+prev].Time).Seconds()
+		if dt <= 0 {
+			continue
+		}
+		raw[i] = (speed[next] - speed[prev]) / dt
+	}
+	acc = movingAverage(raw, window)
+	return
+}
+
+// forwardAxis longitudinal acceleration from ACCL samples whatever the camera
+// mounting: gravity (mean ACCL) is removed and axes are combined by least
+// squares on GPS acceleration between start and stop, nil when fit is poor
+func forwardAxis(gps, accl []Timely, start, stop int, cfg ZoneConfig) func(a ACCL) float64 {
+	if len(accl) < 2 {
+		return nil
+	}
+	var g [3]float64
+	raw := make([][]float64, 3)
+	for i := range raw {
+		raw[i] = make([]float64, len(accl))
+	}
+	for i, a := range accl {
+		v := a.Value.(ACCL)
+		for j, x := range []float64{v.X, v.Y, v.Z} {
+			raw[j][i] = x
+			g[j] += x / float64(len(accl))
+		}
+	}
+	for j := range raw {
+		raw[j] = movingAverage(raw[j], cfg.AcclWindow)
+	}
+	acc := LongitudinalAcc(gps, start, stop, cfg.Window)
+	// normal equations of least squares
+	var ata [3][3]float64
+	var atb [3]float64
+	samples := make([][3]float64, 0, len(acc))
+	targets := make([]float64, 0, len(acc))
+	for i, b := range acc {
+		index := FindIndex(gps[start+i].Time, accl)
+		if index == -1 {
+			continue
+		}
+		x := [3]float64{raw[0][index] - g[0], raw[1][index] - g[1], raw[2][index] - g[2]}
+		for j := range x {
+			for k := range x {
+				ata[j][k] += x[j] * x[k]
+			}
+			atb[j] += x[j] * b
+		}
+		samples = append(samples, x)
+		targets = append(targets, b)
+	}
+	w, ok := solve3(ata, atb)
+	if !ok || len(targets) < 3 {
+		return nil
+	}
+	// part of GPS acceleration explained by fit
+	var res, tot, mean float64
+	for _, b := range targets {
+		mean += b / float64(len(targets))
+	}
+	for i, x := range samples {
+		e := targets[i] - (x[0]*w[0] + x[1]*w[1] + x[2]*w[2])
+		res += e * e
+		tot += (targets[i] - mean) * (targets[i] - mean)
+	}
+	if tot == 0 || 1-res/tot < FORWARD_MIN_R2 {
+		return nil
+	}
+	return func(a ACCL) float64 {
+		return (a.X-g[0])*w[0] + (a.Y-g[1])*w[1] + (a.Z-g[2])*w[2]
+	}
+}
+
+// solve3 m x = b with Cramer's rule, false when m is singular
+func solve3(m [3][3]float64, b [3]float64) (x [3]float64, ok bool) {
+	det := func(m [3][3]float64) float64 {
+		return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+			m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+			m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	}
+	d := det(m)
+	if math.Abs(d) < 1e-12 {
+		return
+	}
+	for i := range x {
+		mi := m
+		for j := range b {
+			mi[j][i] = b[j]
+		}
+		x[i] = det(mi) / d
+	}
+	return x, true
+}
+
+// acclAt filtered longitudinal ACCL, for each GPS sample between start and stop
+func acclAt(gps, accl []Timely, start, stop int, cfg ZoneConfig) (values []float64) {
+	if cfg.Longitudinal == nil || len(accl) < 2 {
+		return
+	}
+	raw := make([]float64, len(accl))
+	for i, a := range accl {
+		raw[i] = cfg.Longitudinal(a.Value.(ACCL))
+	}
+	filtered := movingAverage(raw, cfg.AcclWindow)
+	values = make([]float64, stop-start+1)
+	for i := range values {
+		index := FindIndex(gps[start+i].Time, accl)
+		if index == -1 {
+			// out of ACCL range, no information
+			return nil
+		}
+		values[i] = filtered[index]
+	}
+	return
+}
+
+// DetectZones find braking and throttle zones in gps[start:stop+1]
+// accl is optional, when given zones and peaks are found on its longitudinal
+// acceleration, along cfg.Longitudinal or forward axis fitted on GPS speed,
+// otherwise GPS speed is used
+func DetectZones(gps, accl []Timely, start, stop int, cfg ZoneConfig) (zones []Zone) {
+	zones = make([]Zone, 0)
+	if start < 0 || stop >= len(gps) || stop-start < 2 {
+		return
+	}
+	if cfg.Longitudinal == nil {
+		cfg.Longitudinal = forwardAxis(gps, accl, start, stop, cfg)
+	}
+	acc := acclAt(gps, accl, start, stop, cfg)
+	if acc == nil {
+		acc = LongitudinalAcc(gps, start, stop, cfg.Window)
+	}
+	kindOf := func(a float64) (k ZoneKind, ok bool) {
+		if a <= -cfg.BrakeThreshold {
+			return Braking, true
+		}
+		if a >= cfg.ThrottleThreshold {
+			return Throttle, true
+		}
+		return
+	}
+	closeZone := func(kind ZoneKind, from, to int) {
+		z := Zone{
+			Kind:     kind,
+			Start:    gps[start+from],
+			Stop:     gps[start+to],
+			SpeedIn:  gps[start+from].Value.(GPS5).Speed3D,
+			SpeedOut: gps[start+to].Value.(GPS5).Speed3D,
+		}
+		if z.Duration() < cfg.MinDuration || z.SpeedDelta() < cfg.MinSpeedDelta {
+			return
+		}
+		for i := from; i <= to; i++ {
+			if i == from || (kind == Braking && acc[i] < z.Peak) || (kind == Throttle && acc[i] > z.Peak) {
+				z.Peak = acc[i]
+				z.PeakAt = gps[start+i]
+			}
+		}
+		zones = append(zones, z)
+	}
+	current, inZone := Braking, false
+	from := 0
+	for i, a := range acc {
+		kind, ok := kindOf(a)
+		if inZone && (!ok || kind != current) {
+			closeZone(current, from, i-1)
+			inZone = false
+		}
+		if ok && !inZone {
+			current, inZone, from = kind, true, i
+		}
+	}
+	if inZone {
+		closeZone(current, from, len(acc)-1)
+	}
+	return
+}
+
+// LapRange GPS indexes of given lap start and stop
+func (l LapCounter) LapRange(gps []Timely, lap int) (start, stop int, err error) {
+	if lap < 0 || lap+1 >= len(l.laps) || l.laps[lap][0].IsZero() {
+		err = fmt.Errorf("lap %d is not a complete lap", lap)
+		return
+	}
+	start = FindIndex(l.laps[lap][0], gps)
+	stop = FindIndex(l.laps[lap+1][0], gps)
+	if start == -1 || stop == -1 {
+		err = fmt.Errorf("lap %d out of GPS range", lap)
+	}
+	return
+}
+
+// Zones braking and throttle zones of given lap
+func (l LapCounter) Zones(gps, accl []Timely, lap int, cfg ZoneConfig) (zones []Zone, err error) {
+	start, stop, err := l.LapRange(gps, lap)
+	if err != nil {
+		return
+	}
+	zones = DetectZones(gps, accl, start, stop, cfg)
+	for i := range zones {
+		zones[i].Lap = lap
+	}
+	return
+}
+
+// DrawZones markers on map, filled circle at zone start, empty at zone stop
+// and small one at peak
+func (t Track) DrawZones(img *image.RGBA, zones []Zone) {
+	r := img.Bounds()
+	for _, z := range zones {
+		c := red
+		if z.Kind == Throttle {
+			c = green
+		}
+		start := z.Start.Value.(GPS5)
+		x, y := t.PosToXY(r, start.Latitude, start.Longitude)
+		DrawCircle(img, x, y, 6, c)
+		stop := z.Stop.Value.(GPS5)
+		x, y = t.PosToXY(r, stop.Latitude, stop.Longitude)
+		DrawEmptyCircle(img, x, y, 8, c)
+		if peak, ok := z.PeakAt.Value.(GPS5); ok {
+			x, y = t.PosToXY(r, peak.Latitude, peak.Longitude)
+			DrawCircle(img, x, y, 3, white)
+		}
+	}
+}
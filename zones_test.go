@@ -0,0 +1,92 @@
+package gokart
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// syntheticLap straight line at 10Hz following given speeds (m/s)
+func syntheticLap(speeds []float64) (gps []Timely) {
+	start := time.Date(2024, 9, 14, 11, 12, 0, 0, time.UTC)
+	gps = make([]Timely, len(speeds))
+	for i, s := range speeds {
+		g := NewGPS5(47.0, 0.2+float64(i)*0.00001)
+		g.Speed3D = s
+		gps[i] = Timely{Time: start.Add(time.Duration(i) * 100 * time.Millisecond), Value: g}
+	}
+	return
+}
+
+// brakeAndThrottle cruise, brake from 20 to 10 m/s in 2s, cruise, accelerate to 15 m/s in 2.5s
+func brakeAndThrottle() (speeds []float64) {
+	for i := 0; i < 20; i++ {
+		speeds = append(speeds, 20)
+	}
+	for i := 1; i <= 20; i++ {
+		speeds = append(speeds, 20-float64(i)*0.5)
+	}
+	for i := 0; i < 20; i++ {
+		speeds = append(speeds, 10)
+	}
+	for i := 1; i <= 25; i++ {
+		speeds = append(speeds, 10+float64(i)*0.2)
+	}
+	for i := 0; i < 20; i++ {
+		speeds = append(speeds, 15)
+	}
+	return
+}
+
+func TestDetectZones(t *testing.T) {
+	speeds := brakeAndThrottle()
+	gps := syntheticLap(speeds)
+	zones := DetectZones(gps, nil, 0, len(gps)-1, DefaultZoneConfig)
+	if len(zones) != 2 {
+		t.Fatalf("found %d zones should be 2: %v", len(zones), zones)
+	}
+	if zones[0].Kind != Braking || zones[1].Kind != Throttle {
+		t.Errorf("wrong zone kinds %s %s", zones[0].Kind, zones[1].Kind)
+	}
+	if zones[0].Peak > -4.0 || zones[0].Peak < -5.5 {
+		t.Errorf("braking peak is %f should be around -5", zones[0].Peak)
+	}
+	if zones[0].SpeedDelta() < 7 {
+		t.Errorf("braking speed lost is %f should be around 10", zones[0].SpeedDelta())
+	}
+}
+
+func TestDetectZonesAccl(t *testing.T) {
+	speeds := brakeAndThrottle()
+	gps := syntheticLap(speeds)
+	// camera on its side looking backward at 100Hz: gravity on X, forward is -Y, with vibrations
+	var accl []Timely
+	for i := 1; i < len(gps); i++ {
+		a := (speeds[i] - speeds[i-1]) / 0.1
+		for j := 0; j < 10; j++ {
+			k := float64(i*10 + j)
+			accl = append(accl, Timely{
+				Time:  gps[i-1].Time.Add(time.Duration(j) * 10 * time.Millisecond),
+				Value: ACCL{X: STANDARD_GRAVITY + 0.5*math.Sin(k), Y: -a + 0.5*math.Cos(k*1.3), Z: 0.5 * math.Sin(k*0.7)},
+			})
+		}
+	}
+	zones := DetectZones(gps, accl, 0, len(gps)-1, DefaultZoneConfig)
+	if len(zones) != 2 || zones[0].Kind != Braking || zones[1].Kind != Throttle {
+		t.Fatalf("wrong zones %v", zones)
+	}
+	// braking starts after sample 20 and is read from ACCL
+	if d := zones[0].Start.Time.Sub(gps[20].Time); d < -200*time.Millisecond || d > 200*time.Millisecond {
+		t.Errorf("braking starts %s from sample 20", d)
+	}
+	if math.Abs(zones[0].Peak+5) > 0.5 || math.Abs(zones[1].Peak-2) > 0.3 {
+		t.Errorf("peaks are %f and %f should be -5 and 2", zones[0].Peak, zones[1].Peak)
+	}
+	// unrelated ACCL is not used
+	for i := range accl {
+		accl[i].Value = ACCL{X: STANDARD_GRAVITY + 0.5*math.Sin(float64(i))}
+	}
+	if zones = DetectZones(gps, accl, 0, len(gps)-1, DefaultZoneConfig); len(zones) != 2 || zones[0].Peak > -4.0 {
+		t.Errorf("zones should be found from speed %v", zones)
+	}
+}